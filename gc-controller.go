@@ -3,29 +3,52 @@ package main
 import (
 	"fmt"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/configs"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/diagnostics"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/guard"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/handler"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/hook"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/power"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/schedule"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/serviceerror"
 	"github.com/gin-gonic/gin"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
 )
 
+// runtimeDirEnv points the hook to the runtime dir of the controller, if not the default one.
+const runtimeDirEnv = "GC_CONTROLLER_RUNTIME_DIR"
+
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(doctor())
+	}
+	if len(os.Args) > 1 && os.Args[1] == "hook" {
+		os.Exit(qemuHook())
+	}
+
 	log.Println("loading service configurations...")
 	conf := loadConfigs()
 
+	log.Println("claiming the host for power management...")
+	lock, err := claimHost(conf)
+	if err != nil {
+		fmt.Println("Failed to claim the host", err)
+		return
+	}
+
 	log.Println("creating an api handler...")
 	apiHandler, err := getAPIHandler(conf)
 	if err != nil {
 		fmt.Println("Failed to create an API handler", err)
+		_ = lock.Release()
 		return
 	}
-	attachCleanUponShutdownHandler(apiHandler)
+	attachCleanUponShutdownHandler(apiHandler, lock)
 
 	log.Println("configuring api routing...")
 	router := gin.Default()
@@ -37,6 +60,41 @@ func main() {
 
 	router.PUT("/gc-controller/dev/perf", apiHandler.PutPoolFreq)
 	router.GET("/gc-controller/dev/green-score", apiHandler.GetGreenScore)
+	router.GET("/gc-controller/dev/drift", apiHandler.GetDriftStats)
+	router.GET("/gc-controller/dev/thermal", apiHandler.GetThermalStats)
+	router.GET("/gc-controller/dev/power-budget", apiHandler.GetPowerBudgetStats)
+	router.GET("/gc-controller/dev/workloads", apiHandler.GetWorkloadStats)
+	router.GET("/gc-controller/dev/busy", apiHandler.GetBusyStats)
+
+	router.GET("/gc-controller/v1/diagnostics", apiHandler.GetDiagnostics)
+	router.GET("/gc-controller/v1/autoscaler", apiHandler.GetAutoscaler)
+	router.PUT("/gc-controller/v1/autoscaler", apiHandler.PutAutoscaler)
+	router.GET("/gc-controller/v1/schedules", apiHandler.GetSchedules)
+	router.POST("/gc-controller/v1/schedules/rules", apiHandler.PostScheduleRule)
+	router.DELETE("/gc-controller/v1/schedules/rules/:name", apiHandler.DeleteScheduleRule)
+	router.PUT("/gc-controller/v1/schedules/override", apiHandler.PutScheduleOverride)
+	router.DELETE("/gc-controller/v1/schedules/override", apiHandler.DeleteScheduleOverride)
+	router.GET("/gc-controller/v1/demand-response", apiHandler.GetDemandResponse)
+	router.POST("/gc-controller/v1/demand-response", apiHandler.PostDemandResponse)
+	router.DELETE("/gc-controller/v1/demand-response", apiHandler.DeleteDemandResponse)
+	router.GET("/gc-controller/v1/leases", apiHandler.GetLeases)
+	router.PUT("/gc-controller/v1/leases/:id", apiHandler.PutLease)
+	router.DELETE("/gc-controller/v1/leases/:id", apiHandler.DeleteLease)
+	router.GET("/gc-controller/v1/reservations", apiHandler.GetReservations)
+	router.POST("/gc-controller/v1/reservations", apiHandler.PostReservation)
+	router.DELETE("/gc-controller/v1/reservations/:owner", apiHandler.DeleteReservation)
+	router.GET("/gc-controller/v1/placements", apiHandler.GetPlacements)
+	router.PUT("/gc-controller/v1/placements/:domain", apiHandler.PutPlacement)
+	router.DELETE("/gc-controller/v1/placements/:domain", apiHandler.DeletePlacement)
+	router.GET("/gc-controller/v1/domain-holds", apiHandler.GetDomainHolds)
+	router.PUT("/gc-controller/v1/domain-holds/:domain", apiHandler.PutDomainHold)
+	router.DELETE("/gc-controller/v1/domain-holds/:domain", apiHandler.DeleteDomainHold)
+
+	// the socket belongs to the controller holding the instance lock, which an emulated one does not.
+	if !conf.Host.IsEmulate {
+		log.Println("begin serving on the local socket...")
+		serveLocalSocket(router, conf)
+	}
 
 	log.Println("begin serving...")
 	err = router.Run(conf.Host.Name + ":" + strconv.Itoa(conf.Host.Port))
@@ -46,39 +104,130 @@ func main() {
 	}
 }
 
-func attachCleanUponShutdownHandler(apiHandler *handler.SleepAPIHandler) {
+// serveLocalSocket serves the api on the local socket as well, for callers on the host such as the libvirt hook.
+func serveLocalSocket(router *gin.Engine, conf *model.ConfYaml) {
+	listener, err := guard.ListenLocalSocket(conf.Host.RuntimeDir)
+	if err != nil {
+		log.Println("unable to serve on the local socket: ", err)
+		return
+	}
+	go func() {
+		err := http.Serve(listener, router)
+		if err != nil {
+			log.Println("stopped serving on the local socket: ", err)
+		}
+	}()
+}
+
+func attachCleanUponShutdownHandler(apiHandler *handler.SleepAPIHandler, lock *guard.InstanceLock) {
 	// Set cleanup.
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
 		<-c
 		fmt.Println("[service quit signal received]")
-		cleanup(apiHandler)
+		cleanup(apiHandler, lock)
 		os.Exit(0)
 	}()
 }
 
-func cleanup(handler *handler.SleepAPIHandler) {
+func cleanup(handler *handler.SleepAPIHandler, lock *guard.InstanceLock) {
 	log.Println("restoring core power management...")
 	err := handler.Clean()
 	if err != nil {
 		log.Fatal("unable to restore the system. there can be residue of core power management that might "+
 			"degrade hardware. consider rebooting to properly hand-over power management to the os. ", err)
 	}
+	err = lock.Release()
+	if err != nil {
+		log.Println("unable to release the instance lock: ", err)
+	}
 	log.Println("power management safely handed over to the os. Goodbye!")
 }
 
+// claimHost makes sure no other controller or power manager competes for the cores of this host.
+func claimHost(conf *model.ConfYaml) (*guard.InstanceLock, error) {
+	if conf.Host.IsEmulate {
+		// emulated controllers do not hold the instance lock, and thus keep their state apart from the one of the
+		// controller managing the host.
+		stateDir, err := os.MkdirTemp("", "gc-controller-emulation-")
+		if err != nil {
+			return nil, fmt.Errorf("failed at creating the state dir of the emulation: %w", err)
+		}
+		conf.Host.StateDir = stateDir
+		log.Printf("emulation keeps its state in %s", stateDir)
+		return nil, nil
+	}
+	lock, err := guard.AcquireInstanceLock(conf.Host.RuntimeDir)
+	if err != nil {
+		return nil, err
+	}
+	err = guard.CheckConflicts(conf.Host)
+	if err != nil {
+		_ = lock.Release()
+		return nil, err
+	}
+	return lock, nil
+}
+
 func getAPIHandler(conf *model.ConfYaml) (*handler.SleepAPIHandler, error) {
 	controller, err := power.NewSleepController(conf)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize sleep controller: %w", err)
 	}
+	scheduler, err := schedule.NewScheduler(controller, conf)
+	if err != nil {
+		_ = controller.Clean()
+		return nil, fmt.Errorf("failed to initialize scheduler: %w", err)
+	}
+	controller.StartReconciler()
+	controller.StartThermalGuard()
+	controller.StartPowerBudget()
+	controller.StartBusySampler()
+	controller.StartAutoscaler()
+	controller.StartCarbonPolicy()
+	controller.StartLeaseKeeper()
+	controller.StartReservations()
+	controller.StartPlacements()
+	controller.StartDomainHolds()
+	scheduler.Start()
 	sleepHandler := handler.SleepAPIHandler{
+		Conf:       conf,
 		Controller: controller,
+		Scheduler:  scheduler,
 	}
 	return &sleepHandler, nil
 }
 
+// doctor runs the preflight diagnostics of `gc-controller doctor <config-file>` and returns the exit code.
+func doctor() int {
+	path := ""
+	if len(os.Args) > 2 {
+		path = os.Args[2]
+	}
+	report := diagnostics.Run(configs.NewConfigs(path))
+	fmt.Print(diagnostics.Format(report))
+	if !report.Passed {
+		return 1
+	}
+	return 0
+}
+
+// qemuHook runs `gc-controller hook qemu <domain> <operation> <sub-operation> <extra>` as the libvirt qemu hook, and
+// returns the exit code. Failures are only reported, since a failing hook would abort the domain.
+func qemuHook() int {
+	if len(os.Args) < 6 || os.Args[2] != "qemu" {
+		fmt.Fprintln(os.Stderr, "usage: gc-controller hook qemu <domain> <operation> <sub-operation> [extra]")
+		return 1
+	}
+	socketPath := guard.SocketPath(os.Getenv(runtimeDirEnv))
+	err := hook.RunQemu(os.Args[3], os.Args[4], os.Stdin, socketPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gc-controller hook:", err)
+	}
+	return 0
+}
+
 func loadConfigs() *model.ConfYaml {
 	path := ""
 	if len(os.Args) > 1 {
@@ -17,21 +17,164 @@ func NewConfigs(path string) *model.ConfYaml {
 	loadConfigs(path, k)
 	return &model.ConfYaml{
 		Host: model.Host{
-			Name:      k.String("host.name"),
-			Port:      k.Int("host.port"),
-			IsEmulate: k.Bool("host.is-emulate"),
+			Name:           k.String("host.name"),
+			Port:           k.Int("host.port"),
+			IsEmulate:      k.Bool("host.is-emulate"),
+			RuntimeDir:     k.String("host.runtime-dir"),
+			StateDir:       k.String("host.state-dir"),
+			ConflictPolicy: k.String("host.conflict-policy"),
+			PstateMode:     k.String("host.pstate-mode"),
+			Backend:        k.String("host.backend"),
+			IdleGovernor:   k.String("host.idle-governor"),
 		},
 		Topology: model.Topology{
 			StableCoreCount:  k.Int("topology.stable-core-count"),
 			DynamicCoreCount: k.Int("topology.dynamic-core-count"),
 		},
 		PowerProfile: model.PowerProfile{
-			SleepIdleState: k.String("power-profile.sleep-idle-state"),
-			SleepFrq:       k.Int("power-profile.sleep-frq"),
-			PerfIdleState:  k.String("power-profile.perf-idle-state"),
-			PerfFrq:        k.Int("power-profile.perf-frq"),
+			SleepIdleState:          k.String("power-profile.sleep-idle-state"),
+			SleepFrq:                k.Int("power-profile.sleep-frq"),
+			PerfIdleState:           k.String("power-profile.perf-idle-state"),
+			PerfFrq:                 k.Int("power-profile.perf-frq"),
+			SleepMode:               k.String("power-profile.sleep-mode"),
+			PerfExitLatencyBudgetUs: k.Int("power-profile.perf-exit-latency-budget-us"),
+			StableMaxWakeLatencyUs:  k.Int("power-profile.stable-max-wake-latency-us"),
+			DynamicMaxWakeLatencyUs: k.Int("power-profile.dynamic-max-wake-latency-us"),
+			GlobalMaxWakeLatencyUs:  k.Int("power-profile.global-max-wake-latency-us"),
+			StableTurbo:             boolOrDefault(k, "power-profile.stable-turbo", true),
+			DynamicTurbo:            boolOrDefault(k, "power-profile.dynamic-turbo", true),
+			Uncore:                  getUncoreProfiles(k),
+			UncoreOnSleep:           k.Bool("power-profile.uncore-on-sleep"),
 		},
+		Verification: model.Verification{
+			Enabled:         k.Bool("verification.enabled"),
+			RetryCount:      k.Int("verification.retry-count"),
+			RetryIntervalMs: k.Int("verification.retry-interval-ms"),
+		},
+		Reconciliation: model.Reconciliation{
+			Enabled:     k.Bool("reconciliation.enabled"),
+			IntervalSec: k.Int("reconciliation.interval-sec"),
+			AutoCorrect: k.Bool("reconciliation.auto-correct"),
+		},
+		Thermal: model.Thermal{
+			Enabled:         k.Bool("thermal.enabled"),
+			IntervalSec:     k.Int("thermal.interval-sec"),
+			Sensors:         k.Strings("thermal.sensors"),
+			CapTempC:        k.Int("thermal.cap-temp-c"),
+			CapFrq:          k.Int("thermal.cap-frq"),
+			HysteresisC:     k.Int("thermal.hysteresis-c"),
+			RefuseWakeTempC: k.Int("thermal.refuse-wake-temp-c"),
+		},
+		PowerBudget: model.PowerBudget{
+			Enabled:         k.Bool("power-budget.enabled"),
+			IntervalSec:     k.Int("power-budget.interval-sec"),
+			PackageWatts:    k.Int("power-budget.package-watts"),
+			HysteresisWatts: k.Int("power-budget.hysteresis-watts"),
+			StepFrq:         k.Int("power-budget.step-frq"),
+			ProgramRapl:     k.Bool("power-budget.program-rapl"),
+		},
+		Autoscaler: model.Autoscaler{
+			Enabled:      k.Bool("autoscaler.enabled"),
+			IntervalSec:  k.Int("autoscaler.interval-sec"),
+			HighWaterPct: k.Int("autoscaler.high-water-pct"),
+			LowWaterPct:  k.Int("autoscaler.low-water-pct"),
+			IdleSec:      k.Int("autoscaler.idle-sec"),
+			MinAwakeSec:  k.Int("autoscaler.min-awake-sec"),
+			MinAsleepSec: k.Int("autoscaler.min-asleep-sec"),
+		},
+		Schedules: getScheduleRules(k),
+		Carbon: model.Carbon{
+			Enabled:          k.Bool("carbon.enabled"),
+			Source:           k.String("carbon.source"),
+			IntervalSec:      k.Int("carbon.interval-sec"),
+			MaxAgeSec:        intOrDefault(k, "carbon.max-age-sec", 3600),
+			HighIntensity:    k.Int("carbon.high-intensity"),
+			LowIntensity:     k.Int("carbon.low-intensity"),
+			EcoFrq:           k.Int("carbon.eco-frq"),
+			MinFrq:           k.Int("carbon.min-frq"),
+			SleepOnHigh:      k.Bool("carbon.sleep-on-high"),
+			MaxStableUtilPct: k.Int("carbon.max-stable-util-pct"),
+		},
+		DemandResponse: model.DemandResponse{
+			CapFrq:         k.Int("demand-response.cap-frq"),
+			ProtectedPools: stringsOrDefault(k, "demand-response.protected-pools", []string{"stbl-pool"}),
+		},
+		Leases: model.Leases{
+			DefaultTtlSec: k.Int("leases.default-ttl-sec"),
+			IntervalSec:   k.Int("leases.interval-sec"),
+		},
+		Drain: model.Drain{
+			Policy:     stringOrDefault(k, "drain.policy", "none"),
+			Libvirt:    k.Bool("drain.libvirt"),
+			CgroupRoot: k.String("drain.cgroup-root"),
+		},
+		Utilization: model.Utilization{
+			Source:     k.String("utilization.source"),
+			CgroupRoot: k.String("utilization.cgroup-root"),
+		},
+		BusySampler: model.BusySampler{
+			Enabled:     k.Bool("busy-sampler.enabled"),
+			IntervalSec: k.Int("busy-sampler.interval-sec"),
+		},
+	}
+}
+
+func getUncoreProfiles(k *koanf.Koanf) []model.UncoreProfile {
+	var profiles []model.UncoreProfile
+	for _, uncore := range k.Slices("power-profile.uncore") {
+		profiles = append(profiles, model.UncoreProfile{
+			Package:     uncore.Int("package"),
+			PerfMinFrq:  uncore.Int("perf-min-frq"),
+			PerfMaxFrq:  uncore.Int("perf-max-frq"),
+			SleepMinFrq: uncore.Int("sleep-min-frq"),
+			SleepMaxFrq: uncore.Int("sleep-max-frq"),
+		})
+	}
+	return profiles
+}
+
+func getScheduleRules(k *koanf.Koanf) []model.ScheduleRule {
+	var rules []model.ScheduleRule
+	for _, rule := range k.Slices("schedules") {
+		rules = append(rules, model.ScheduleRule{
+			Name:   rule.String("name"),
+			Action: rule.String("action"),
+			FMhz:   uint(rule.Int("f-mhz")),
+			Pool:   rule.String("pool"),
+			Days:   rule.Strings("days"),
+			Start:  rule.String("start"),
+			End:    rule.String("end"),
+		})
+	}
+	return rules
+}
+
+func boolOrDefault(k *koanf.Koanf, path string, defaultValue bool) bool {
+	if !k.Exists(path) {
+		return defaultValue
+	}
+	return k.Bool(path)
+}
+
+func intOrDefault(k *koanf.Koanf, path string, defaultValue int) int {
+	if !k.Exists(path) {
+		return defaultValue
+	}
+	return k.Int(path)
+}
+
+func stringOrDefault(k *koanf.Koanf, path string, defaultValue string) string {
+	if k.String(path) == "" {
+		return defaultValue
+	}
+	return k.String(path)
+}
+
+func stringsOrDefault(k *koanf.Koanf, path string, defaultValue []string) []string {
+	if !k.Exists(path) {
+		return defaultValue
 	}
+	return k.Strings(path)
 }
 
 func loadConfigs(path string, k *koanf.Koanf) {
@@ -0,0 +1,234 @@
+package diagnostics
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/guard"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/power"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+
+	capSysAdminBit = 21
+	accessWriteOk  = 0x2
+)
+
+// Run verifies that the host meets the requirements of the controller for the given configurations. Only failed
+// checks mark the report as not passed.
+func Run(conf *model.ConfYaml) model.DiagnosticsReport {
+	checks := []model.DiagnosticCheck{
+		checkPrivileges(),
+		checkSysfsWritable(),
+		checkIdleDriver(conf.Host),
+		checkCpufreqDriver(conf.Host),
+		checkIdleStates(conf.PowerProfile),
+		checkFrequencies(conf.PowerProfile),
+		checkTopology(conf.Topology),
+		checkCompetingPowerManagers(conf.Host),
+	}
+	report := model.DiagnosticsReport{Passed: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == StatusFail {
+			report.Passed = false
+		}
+	}
+	return report
+}
+
+// Format renders the report as a human-readable pass/fail list.
+func Format(report model.DiagnosticsReport) string {
+	var sb strings.Builder
+	for _, check := range report.Checks {
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", strings.ToUpper(check.Status), check.Name, check.Detail))
+		if check.Remedy != "" && check.Status != StatusPass {
+			sb.WriteString(fmt.Sprintf("       -> %s\n", check.Remedy))
+		}
+	}
+	if report.Passed {
+		sb.WriteString("host is ready for gc-controller\n")
+	} else {
+		sb.WriteString("host is not ready for gc-controller, fix the failed checks above\n")
+	}
+	return sb.String()
+}
+
+func newCheck(name string, status string, detail string, remedy string) model.DiagnosticCheck {
+	return model.DiagnosticCheck{Name: name, Status: status, Detail: detail, Remedy: remedy}
+}
+
+func checkPrivileges() model.DiagnosticCheck {
+	name := "privileges"
+	if os.Geteuid() == 0 {
+		return newCheck(name, StatusPass, "running as root", "")
+	}
+	status, err := os.ReadFile("/proc/self/status")
+	if err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			capEff, found := strings.CutPrefix(line, "CapEff:")
+			if !found {
+				continue
+			}
+			caps, err := strconv.ParseUint(strings.TrimSpace(capEff), 16, 64)
+			if err == nil && caps&(1<<capSysAdminBit) != 0 {
+				return newCheck(name, StatusPass, "running with CAP_SYS_ADMIN", "")
+			}
+		}
+	}
+	return newCheck(name, StatusFail, "not running as root nor with CAP_SYS_ADMIN",
+		"run gc-controller with sudo")
+}
+
+func checkSysfsWritable() model.DiagnosticCheck {
+	name := "sysfs-writable"
+	paths := []string{
+		sysfs.CpuFile(0, sysfs.ScalingMaxFreqFile),
+		sysfs.IdleStateFile(0, 0, "disable"),
+	}
+	var readOnly []string
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if syscall.Access(path, accessWriteOk) != nil {
+			readOnly = append(readOnly, path)
+		}
+	}
+	if len(readOnly) > 0 {
+		return newCheck(name, StatusFail, fmt.Sprintf("not writable: %s", strings.Join(readOnly, ", ")),
+			"run gc-controller with sudo, and make sure /sys is not mounted read-only (ex: inside a container)")
+	}
+	return newCheck(name, StatusPass, "cpufreq and cpuidle attributes are writable", "")
+}
+
+func checkIdleDriver(conf model.Host) model.DiagnosticCheck {
+	name := "idle-driver"
+	driver, err := sysfs.ReadString(sysfs.CpuPath + "/" + sysfs.IdleDriverFile)
+	if err != nil {
+		return newCheck(name, StatusWarn, fmt.Sprintf("unable to read the idle driver: %v", err),
+			"idle states will not be managed. boot with cpuidle enabled for c-state support")
+	}
+	switch driver {
+	case "intel_idle":
+		return newCheck(name, StatusPass, driver, "")
+	case "acpi_idle":
+		return newCheck(name, StatusWarn, driver+" exposes only the ACPI idle states",
+			"boot without intel_idle.max_cstate=0 to use intel_idle for deeper idle states")
+	case "none":
+		return newCheck(name, StatusWarn, "no idle driver is loaded",
+			"idle states will not be managed. boot with cpuidle enabled for c-state support")
+	}
+	if power.ResolveBackend(conf) != power.IntelBackend {
+		return newCheck(name, StatusPass, fmt.Sprintf("%s (managed by the sysfs backend)", driver), "")
+	}
+	return newCheck(name, StatusWarn, fmt.Sprintf("unsupported idle driver: %s", driver),
+		"idle states will not be managed. use intel_idle or acpi_idle, or set host.backend to sysfs")
+}
+
+func checkCpufreqDriver(conf model.Host) model.DiagnosticCheck {
+	name := "cpufreq-driver"
+	driver, err := sysfs.ReadString(sysfs.CpuFile(0, sysfs.ScalingDriverFile))
+	if err != nil {
+		return newCheck(name, StatusWarn, fmt.Sprintf("unable to read the cpufreq driver: %v", err),
+			"frequencies will not be managed. enable cpufreq for frequency scaling support")
+	}
+	if !slices.Contains([]string{"intel_pstate", "intel_cpufreq", "acpi-cpufreq"}, driver) {
+		if power.ResolveBackend(conf) != power.IntelBackend {
+			return newCheck(name, StatusPass, fmt.Sprintf("%s (managed by the sysfs backend)", driver), "")
+		}
+		return newCheck(name, StatusWarn, fmt.Sprintf("unsupported cpufreq driver: %s", driver),
+			"frequencies will not be managed. use intel_pstate or acpi-cpufreq, or set host.backend to sysfs")
+	}
+	mode, _ := guard.ReadPstateMode()
+	if mode != "" {
+		return newCheck(name, StatusPass, fmt.Sprintf("%s (%s mode)", driver, mode), "")
+	}
+	return newCheck(name, StatusPass, driver, "")
+}
+
+func checkIdleStates(conf model.PowerProfile) model.DiagnosticCheck {
+	name := "idle-states"
+	available, err := sysfs.IdleStateNames(0)
+	if err != nil || len(available) == 0 {
+		return newCheck(name, StatusWarn, "no idle states are exposed by the platform",
+			"idle states will not be managed")
+	}
+	var missing []string
+	for _, state := range []string{conf.PerfIdleState, conf.SleepIdleState} {
+		if !slices.Contains(available, state) {
+			missing = append(missing, state)
+		}
+	}
+	if len(missing) > 0 {
+		return newCheck(name, StatusFail, fmt.Sprintf("%s not available. available idle states: %v",
+			strings.Join(missing, ", "), available),
+			"set power-profile.perf-idle-state and power-profile.sleep-idle-state to available idle states")
+	}
+	return newCheck(name, StatusPass, fmt.Sprintf("%s and %s are available", conf.PerfIdleState,
+		conf.SleepIdleState), "")
+}
+
+func checkFrequencies(conf model.PowerProfile) model.DiagnosticCheck {
+	name := "frequencies"
+	minFKHz, err1 := sysfs.ReadUint(sysfs.CpuFile(0, sysfs.CpuInfoMinFreqFile))
+	maxFKHz, err2 := sysfs.ReadUint(sysfs.CpuFile(0, sysfs.CpuInfoMaxFreqFile))
+	if err1 != nil || err2 != nil {
+		return newCheck(name, StatusWarn, "unable to read the cpu frequency range",
+			"frequencies will not be managed")
+	}
+	minFMhz, maxFMhz := int(minFKHz/1000), int(maxFKHz/1000)
+	var outOfRange []string
+	for _, fMhz := range []int{conf.SleepFrq, conf.PerfFrq} {
+		if fMhz < minFMhz || fMhz > maxFMhz {
+			outOfRange = append(outOfRange, strconv.Itoa(fMhz))
+		}
+	}
+	if len(outOfRange) > 0 {
+		return newCheck(name, StatusFail, fmt.Sprintf("%s MHz outside of the supported range: %d-%d MHz",
+			strings.Join(outOfRange, ", "), minFMhz, maxFMhz),
+			"set power-profile.sleep-frq and power-profile.perf-frq within the supported range, otherwise the "+
+				"firmware clamps them")
+	}
+	return newCheck(name, StatusPass, fmt.Sprintf("%d and %d MHz are within %d-%d MHz", conf.SleepFrq,
+		conf.PerfFrq, minFMhz, maxFMhz), "")
+}
+
+func checkTopology(conf model.Topology) model.DiagnosticCheck {
+	name := "topology"
+	cpuIds, err := sysfs.CpuIds()
+	if err != nil {
+		return newCheck(name, StatusFail, err.Error(), "")
+	}
+	reqCoreCount := conf.StableCoreCount + conf.DynamicCoreCount
+	if reqCoreCount > len(cpuIds) {
+		return newCheck(name, StatusFail, fmt.Sprintf("required core count: %d, but only %d available",
+			reqCoreCount, len(cpuIds)),
+			"reduce topology.stable-core-count or topology.dynamic-core-count")
+	}
+	return newCheck(name, StatusPass, fmt.Sprintf("%d of %d cores are managed", reqCoreCount, len(cpuIds)), "")
+}
+
+func checkCompetingPowerManagers(conf model.Host) model.DiagnosticCheck {
+	name := "competing-power-managers"
+	managers, err := guard.FindCompetingPowerManagers()
+	if err != nil {
+		return newCheck(name, StatusWarn, err.Error(), "")
+	}
+	if len(managers) > 0 {
+		status := StatusFail
+		if conf.ConflictPolicy == guard.ConflictPolicyWarn {
+			status = StatusWarn
+		}
+		return newCheck(name, status, fmt.Sprintf("running: %s", strings.Join(managers, ", ")),
+			"stop and disable them (ex: systemctl disable --now tuned)")
+	}
+	return newCheck(name, StatusPass, "no competing power managers are running", "")
+}
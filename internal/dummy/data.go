@@ -7,9 +7,12 @@ import (
 
 var DefaultConfigsBytes, _ = yaml.Marshal(&model.ConfYaml{
 	Host: model.Host{
-		Name:      "localhost",
-		Port:      3000,
-		IsEmulate: false,
+		Name:           "localhost",
+		Port:           3000,
+		IsEmulate:      false,
+		RuntimeDir:     "/run/gc-controller",
+		StateDir:       "/var/lib/gc-controller",
+		ConflictPolicy: "refuse",
 	},
 	Topology: model.Topology{
 		StableCoreCount:  4,
@@ -20,5 +23,77 @@ var DefaultConfigsBytes, _ = yaml.Marshal(&model.ConfYaml{
 		SleepFrq:       400,
 		PerfIdleState:  "POLL",
 		PerfFrq:        2800,
+		SleepMode:      "idle",
+		StableTurbo:    true,
+		DynamicTurbo:   true,
+		UncoreOnSleep:  false,
+	},
+	Verification: model.Verification{
+		Enabled:         true,
+		RetryCount:      2,
+		RetryIntervalMs: 100,
+	},
+	Reconciliation: model.Reconciliation{
+		Enabled:     true,
+		IntervalSec: 30,
+		AutoCorrect: false,
+	},
+	Thermal: model.Thermal{
+		Enabled:         false,
+		IntervalSec:     5,
+		CapTempC:        90,
+		CapFrq:          1600,
+		HysteresisC:     5,
+		RefuseWakeTempC: 95,
+	},
+	PowerBudget: model.PowerBudget{
+		Enabled:         false,
+		IntervalSec:     2,
+		PackageWatts:    65,
+		HysteresisWatts: 5,
+		StepFrq:         200,
+		ProgramRapl:     false,
+	},
+	Autoscaler: model.Autoscaler{
+		Enabled:      false,
+		IntervalSec:  10,
+		HighWaterPct: 80,
+		LowWaterPct:  50,
+		IdleSec:      300,
+		MinAwakeSec:  600,
+		MinAsleepSec: 60,
+	},
+	Carbon: model.Carbon{
+		Enabled:          false,
+		Source:           "/var/lib/gc-controller/carbon-intensity.json",
+		IntervalSec:      300,
+		MaxAgeSec:        3600,
+		HighIntensity:    400,
+		LowIntensity:     300,
+		EcoFrq:           1600,
+		MinFrq:           1200,
+		SleepOnHigh:      false,
+		MaxStableUtilPct: 50,
+	},
+	DemandResponse: model.DemandResponse{
+		CapFrq:         1200,
+		ProtectedPools: []string{"stbl-pool"},
+	},
+	Leases: model.Leases{
+		DefaultTtlSec: 0,
+		IntervalSec:   5,
+	},
+	Drain: model.Drain{
+		Policy:     "none",
+		Libvirt:    true,
+		CgroupRoot: "",
+	},
+	Utilization: model.Utilization{
+		Source:     "libvirt",
+		CgroupRoot: "kubepods.slice",
+	},
+	BusySampler: model.BusySampler{
+		Enabled:     false,
+		IntervalSec: 5,
 	},
 })
@@ -0,0 +1,95 @@
+package guard
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	ConflictPolicyRefuse = "refuse"
+	ConflictPolicyWarn   = "warn"
+
+	pstateStatusPath = "/sys/devices/system/cpu/intel_pstate/status"
+)
+
+// KnownPowerManagers are daemons which change cpufreq or cpuidle settings, and thus compete with the controller.
+var KnownPowerManagers = []string{"tuned", "power-profiles-daemon", "auto-cpufreq"}
+
+// FindCompetingPowerManagers lists running processes that are known to manage core power.
+func FindCompetingPowerManagers() ([]string, error) {
+	comms, err := filepath.Glob("/proc/[0-9]*/comm")
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing processes: %w", err)
+	}
+	var found []string
+	for _, comm := range comms {
+		name, err := os.ReadFile(comm)
+		if err != nil {
+			// process exited while listing.
+			continue
+		}
+		process := strings.TrimSpace(string(name))
+		if slices.Contains(KnownPowerManagers, process) && !slices.Contains(found, process) {
+			found = append(found, process)
+		}
+	}
+	return found, nil
+}
+
+// ReadPstateMode returns the intel_pstate operating mode (active, passive or off), or an empty string if the
+// intel_pstate driver is not in use.
+func ReadPstateMode() (string, error) {
+	status, err := os.ReadFile(pstateStatusPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed at reading intel_pstate status: %w", err)
+	}
+	return strings.TrimSpace(string(status)), nil
+}
+
+// CheckConflicts detects competing power managers and unexpected cpufreq driver modes. Conflicts fail the check
+// unless the host conflict policy is set to warn.
+func CheckConflicts(conf model.Host) error {
+	var conflicts []string
+	managers, err := FindCompetingPowerManagers()
+	if err != nil {
+		return err
+	}
+	for _, manager := range managers {
+		conflicts = append(conflicts, fmt.Sprintf("%s is running and might override core power settings", manager))
+	}
+
+	mode, err := ReadPstateMode()
+	if err != nil {
+		return err
+	}
+	if mode != "" {
+		log.Printf("intel_pstate driver is in %s mode", mode)
+	}
+	if conf.PstateMode != "" && mode == "" {
+		conflicts = append(conflicts, fmt.Sprintf("intel_pstate driver is not in use, but '%s' mode is required",
+			conf.PstateMode))
+	} else if conf.PstateMode != "" && mode != conf.PstateMode {
+		conflicts = append(conflicts, fmt.Sprintf("intel_pstate mode is '%s', but '%s' is required", mode,
+			conf.PstateMode))
+	}
+
+	if len(conflicts) == 0 {
+		return nil
+	}
+	if conf.ConflictPolicy == ConflictPolicyWarn {
+		for _, conflict := range conflicts {
+			log.Printf("WARNING: %s", conflict)
+		}
+		return nil
+	}
+	return fmt.Errorf("competing power management detected: %s", strings.Join(conflicts, "; "))
+}
@@ -0,0 +1,56 @@
+package guard
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+const (
+	DefaultRuntimeDir = "/run/gc-controller"
+	lockFileName      = "gc-controller.lock"
+)
+
+// InstanceLock guarantees a single gc-controller per host, such that controllers do not fight over the same pools.
+type InstanceLock struct {
+	file *os.File
+}
+
+func AcquireInstanceLock(runtimeDir string) (*InstanceLock, error) {
+	if runtimeDir == "" {
+		runtimeDir = DefaultRuntimeDir
+	}
+	err := os.MkdirAll(runtimeDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed at creating runtime dir: %s: %w", runtimeDir, err)
+	}
+	path := filepath.Join(runtimeDir, lockFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed at opening lock file: %s: %w", path, err)
+	}
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		owner, _ := os.ReadFile(path)
+		_ = file.Close()
+		return nil, fmt.Errorf("another gc-controller (pid: %s) holds the lock: %s: %w", string(owner), path, err)
+	}
+
+	// record the owner for operators, the lock itself is held by the open file.
+	_ = file.Truncate(0)
+	_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	return &InstanceLock{file: file}, nil
+}
+
+func (l *InstanceLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	if err != nil {
+		return fmt.Errorf("failed at releasing instance lock: %w", err)
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,45 @@
+package guard
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+const socketFileName = "gc-controller.sock"
+
+// SocketPath returns the path of the local socket the controller serves its api on, for callers on the same host
+// such as libvirt hooks.
+func SocketPath(runtimeDir string) string {
+	if runtimeDir == "" {
+		runtimeDir = DefaultRuntimeDir
+	}
+	return filepath.Join(runtimeDir, socketFileName)
+}
+
+// ListenLocalSocket listens on the local socket, replacing the one left behind by a previous controller. Only the
+// owner can connect, since the api controls the power of the host.
+func ListenLocalSocket(runtimeDir string) (net.Listener, error) {
+	path := SocketPath(runtimeDir)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed at creating runtime dir: %s: %w", filepath.Dir(path), err)
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed at removing stale socket: %s: %w", path, err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed at listening on socket: %s: %w", path, err)
+	}
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed at restricting socket: %s: %w", path, err)
+	}
+	return listener, nil
+}
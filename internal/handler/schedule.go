@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/schedule"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/serviceerror"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+func (o *SleepAPIHandler) GetSchedules(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Scheduler.Status())
+}
+
+func (o *SleepAPIHandler) PostScheduleRule(c *gin.Context) {
+	var rule model.ScheduleRule
+	if err := c.BindJSON(&rule); err != nil {
+		return
+	}
+	if err := schedule.ValidateRule(rule); err != nil {
+		c.Error(serviceerror.NewHttpError("invalid schedule rule", err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	rule.Source = schedule.APISource
+	err := o.Scheduler.AddRule(rule)
+	if err != nil {
+		c.Error(toScheduleHttpError(err))
+		return
+	}
+	o.Scheduler.Refresh()
+
+	c.IndentedJSON(http.StatusCreated, rule)
+}
+
+func (o *SleepAPIHandler) DeleteScheduleRule(c *gin.Context) {
+	err := o.Scheduler.RemoveRule(c.Param("name"))
+	if err != nil {
+		c.Error(toScheduleHttpError(err))
+		return
+	}
+	o.Scheduler.Refresh()
+
+	c.Status(http.StatusNoContent)
+}
+
+func (o *SleepAPIHandler) PutScheduleOverride(c *gin.Context) {
+	var overrideOp model.ScheduleOverrideOp
+	if err := c.BindJSON(&overrideOp); err != nil {
+		return
+	}
+	if err := schedule.ValidateOverride(overrideOp); err != nil {
+		c.Error(serviceerror.NewHttpError("invalid schedule override", err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	err := o.Scheduler.SetOverride(overrideOp)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	o.Scheduler.Refresh()
+
+	c.IndentedJSON(http.StatusCreated, o.Scheduler.Status())
+}
+
+func (o *SleepAPIHandler) DeleteScheduleOverride(c *gin.Context) {
+	err := o.Scheduler.ClearOverride()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	o.Scheduler.Refresh()
+
+	c.Status(http.StatusNoContent)
+}
+
+func toScheduleHttpError(err error) error {
+	switch {
+	case errors.Is(err, schedule.ErrRuleExists), errors.Is(err, schedule.ErrConfigRule):
+		return serviceerror.NewHttpError("unable to change schedule rules", err.Error(), http.StatusConflict)
+	case errors.Is(err, schedule.ErrRuleNotFound):
+		return serviceerror.NewHttpError("unable to change schedule rules", err.Error(), http.StatusNotFound)
+	}
+	return err
+}
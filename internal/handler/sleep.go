@@ -1,14 +1,22 @@
 package handler
 
 import (
+	"errors"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/diagnostics"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/power"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/schedule"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/serviceerror"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type SleepAPIHandler struct {
+	Conf       *model.ConfYaml
 	Controller *power.SleepController
+	Scheduler  *schedule.Scheduler
 }
 
 func (o *SleepAPIHandler) GetSleepInfo(c *gin.Context) {
@@ -17,10 +25,37 @@ func (o *SleepAPIHandler) GetSleepInfo(c *gin.Context) {
 }
 
 func (o *SleepAPIHandler) PutSleepOP(c *gin.Context) {
+	mode := c.Query("mode")
+	if mode != "" && mode != power.IdleSleepMode && mode != power.OfflineSleepMode {
+		c.Error(serviceerror.NewHttpError("unknown sleep mode", mode, http.StatusBadRequest))
+		return
+	}
+
+	drain := c.Query("drain")
+	if drain != "" && drain != power.NoDrainPolicy && drain != power.RefuseDrainPolicy &&
+		drain != power.MigrateDrainPolicy {
+		c.Error(serviceerror.NewHttpError("unknown drain policy", drain, http.StatusBadRequest))
+		return
+	}
+	force := false
+	if forceParam := c.Query("force"); forceParam != "" {
+		var err error
+		force, err = strconv.ParseBool(forceParam)
+		if err != nil {
+			c.Error(serviceerror.NewHttpError("force must be a boolean", forceParam, http.StatusBadRequest))
+			return
+		}
+	}
+
 	controller := o.Controller
-	err := controller.Sleep()
+	var err error
+	if force {
+		err = controller.SleepInMode(mode)
+	} else {
+		err = controller.DrainAndSleep(mode, drain)
+	}
 	if err != nil {
-		c.Error(err)
+		c.Error(toHttpError(err))
 		return
 	}
 
@@ -28,10 +63,30 @@ func (o *SleepAPIHandler) PutSleepOP(c *gin.Context) {
 }
 
 func (o *SleepAPIHandler) PutAwakeOP(c *gin.Context) {
+	ttlSec := o.Controller.DefaultLeaseTtlSec()
+	if ttlParam := c.Query("ttl-sec"); ttlParam != "" {
+		var err error
+		ttlSec, err = strconv.Atoi(ttlParam)
+		if err != nil || ttlSec <= 0 {
+			c.Error(serviceerror.NewHttpError("ttl must be a positive number of seconds", ttlParam,
+				http.StatusBadRequest))
+			return
+		}
+	}
+
 	controller := o.Controller
-	err := controller.Wake()
+	if ttlSec > 0 {
+		lease, err := controller.WakeWithLease(time.Duration(ttlSec) * time.Second)
+		if err != nil {
+			c.Error(toHttpError(err))
+			return
+		}
+		c.IndentedJSON(http.StatusCreated, lease)
+		return
+	}
+	err := controller.WakeWithoutLease()
 	if err != nil {
-		c.Error(err)
+		c.Error(toHttpError(err))
 		return
 	}
 
@@ -47,7 +102,7 @@ func (o *SleepAPIHandler) PutPoolFreq(c *gin.Context) {
 	controller := o.Controller
 	err := controller.OpFrequency(newFqOp.FMhz)
 	if err != nil {
-		c.Error(err)
+		c.Error(toHttpError(err))
 		return
 	}
 
@@ -55,6 +110,7 @@ func (o *SleepAPIHandler) PutPoolFreq(c *gin.Context) {
 }
 
 func (o *SleepAPIHandler) Clean() error {
+	o.Scheduler.Stop()
 	return o.Controller.Clean()
 }
 
@@ -69,6 +125,238 @@ func (o *SleepAPIHandler) GetGreenScore(c *gin.Context) {
 	c.IndentedJSON(http.StatusOK, newGreenScore)
 }
 
+func (o *SleepAPIHandler) GetDriftStats(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.DriftStats())
+}
+
+func (o *SleepAPIHandler) GetThermalStats(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.ThermalStats())
+}
+
+func (o *SleepAPIHandler) GetPowerBudgetStats(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.PowerBudgetStats())
+}
+
+func (o *SleepAPIHandler) GetAutoscaler(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.AutoscalerStatus())
+}
+
+func (o *SleepAPIHandler) PutAutoscaler(c *gin.Context) {
+	var autoscalerOp model.AutoscalerOp
+	if err := c.BindJSON(&autoscalerOp); err != nil {
+		return
+	}
+	if autoscalerOp.Mode != power.AutoscalerAutoMode && autoscalerOp.Mode != power.AutoscalerManualMode {
+		c.Error(serviceerror.NewHttpError("unknown autoscaler mode", autoscalerOp.Mode, http.StatusBadRequest))
+		return
+	}
+
+	err := o.Controller.SetAutoscalerMode(autoscalerOp.Mode)
+	if err != nil {
+		c.Error(serviceerror.NewHttpError("unable to switch autoscaler mode", err.Error(), http.StatusConflict))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, o.Controller.AutoscalerStatus())
+}
+
+func (o *SleepAPIHandler) GetDemandResponse(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.DemandResponseStatus())
+}
+
+func (o *SleepAPIHandler) PostDemandResponse(c *gin.Context) {
+	var demandResponseOp model.DemandResponseOp
+	if err := c.BindJSON(&demandResponseOp); err != nil {
+		return
+	}
+	if demandResponseOp.Level < power.DemandResponseCapLevel || demandResponseOp.Level > power.DemandResponseOfflineLevel {
+		c.Error(serviceerror.NewHttpError("unknown shed level", strconv.Itoa(demandResponseOp.Level),
+			http.StatusBadRequest))
+		return
+	}
+	if demandResponseOp.DurationMin <= 0 {
+		c.Error(serviceerror.NewHttpError("duration must be positive", strconv.Itoa(demandResponseOp.DurationMin),
+			http.StatusBadRequest))
+		return
+	}
+
+	err := o.Controller.StartDemandResponse(demandResponseOp.Level,
+		time.Duration(demandResponseOp.DurationMin)*time.Minute)
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, o.Controller.DemandResponseStatus())
+}
+
+func (o *SleepAPIHandler) DeleteDemandResponse(c *gin.Context) {
+	err := o.Controller.EndDemandResponse()
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (o *SleepAPIHandler) GetLeases(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.Leases())
+}
+
+func (o *SleepAPIHandler) PutLease(c *gin.Context) {
+	var leaseOp model.LeaseOp
+	if err := c.BindJSON(&leaseOp); err != nil {
+		return
+	}
+	if leaseOp.TtlSec <= 0 {
+		c.Error(serviceerror.NewHttpError("ttl must be a positive number of seconds", strconv.Itoa(leaseOp.TtlSec),
+			http.StatusBadRequest))
+		return
+	}
+
+	lease, err := o.Controller.RenewLease(c.Param("id"), time.Duration(leaseOp.TtlSec)*time.Second)
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, lease)
+}
+
+func (o *SleepAPIHandler) DeleteLease(c *gin.Context) {
+	err := o.Controller.ReleaseLease(c.Param("id"))
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (o *SleepAPIHandler) GetReservations(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.Reservations())
+}
+
+func (o *SleepAPIHandler) PostReservation(c *gin.Context) {
+	var reservationOp model.ReservationOp
+	if err := c.BindJSON(&reservationOp); err != nil {
+		return
+	}
+	if reservationOp.Owner == "" {
+		c.Error(serviceerror.NewHttpError("reservation needs an owner", "", http.StatusBadRequest))
+		return
+	}
+	if reservationOp.CoreCount <= 0 {
+		c.Error(serviceerror.NewHttpError("core count must be positive", strconv.Itoa(reservationOp.CoreCount),
+			http.StatusBadRequest))
+		return
+	}
+
+	reservation, err := o.Controller.Reserve(reservationOp.Owner, reservationOp.CoreCount)
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, reservation)
+}
+
+func (o *SleepAPIHandler) DeleteReservation(c *gin.Context) {
+	err := o.Controller.Release(c.Param("owner"))
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (o *SleepAPIHandler) GetPlacements(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.Placements())
+}
+
+func (o *SleepAPIHandler) PutPlacement(c *gin.Context) {
+	var placementOp model.PlacementOp
+	if err := c.BindJSON(&placementOp); err != nil {
+		return
+	}
+
+	placement, err := o.Controller.Place(c.Param("domain"), placementOp.Pool)
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, placement)
+}
+
+func (o *SleepAPIHandler) DeletePlacement(c *gin.Context) {
+	err := o.Controller.ForgetPlacement(c.Param("domain"))
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (o *SleepAPIHandler) GetDomainHolds(c *gin.Context) {
+	c.IndentedJSON(http.StatusOK, o.Controller.DomainHolds())
+}
+
+func (o *SleepAPIHandler) PutDomainHold(c *gin.Context) {
+	var domainHoldOp model.DomainHoldOp
+	if err := c.BindJSON(&domainHoldOp); err != nil {
+		return
+	}
+
+	hold, err := o.Controller.HoldForDomain(c.Param("domain"), domainHoldOp.PinnedCoreIds)
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, hold)
+}
+
+func (o *SleepAPIHandler) DeleteDomainHold(c *gin.Context) {
+	err := o.Controller.ReleaseDomain(c.Param("domain"))
+	if err != nil {
+		c.Error(toHttpError(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (o *SleepAPIHandler) GetWorkloadStats(c *gin.Context) {
+	stats, err := o.Controller.WorkloadStats()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, stats)
+}
+
+func (o *SleepAPIHandler) GetBusyStats(c *gin.Context) {
+	stats := o.Controller.BusyStats()
+	if stats == nil {
+		c.Error(toHttpError(power.ErrNoBusySample))
+		return
+	}
+	c.IndentedJSON(http.StatusOK, stats)
+}
+
+func (o *SleepAPIHandler) GetDiagnostics(c *gin.Context) {
+	// emulated controllers do not touch the host, and thus have no requirements on it.
+	if o.Conf.Host.IsEmulate {
+		c.IndentedJSON(http.StatusOK, model.DiagnosticsReport{Passed: true})
+		return
+	}
+	c.IndentedJSON(http.StatusOK, diagnostics.Run(o.Conf))
+}
+
 func (o *SleepAPIHandler) GetPowerStats(c *gin.Context) {
 	//var newPowerStats model.PowerStats
 	//controller := o.Controller
@@ -79,3 +367,57 @@ func (o *SleepAPIHandler) GetPowerStats(c *gin.Context) {
 	//}
 	//c.IndentedJSON(http.StatusOK, newPowerStats)
 }
+
+func toHttpError(err error) error {
+	var verificationErr *power.VerificationError
+	if errors.As(err, &verificationErr) {
+		return serviceerror.NewHttpError("cores did not reflect the requested power settings",
+			verificationErr.Error(), http.StatusInternalServerError)
+	}
+	var unsupportedErr *power.UnsupportedOperationError
+	if errors.As(err, &unsupportedErr) {
+		return serviceerror.NewHttpError("operation is not supported by the platform", unsupportedErr.Error(),
+			http.StatusNotImplemented)
+	}
+	if errors.Is(err, power.ErrLeaseNotFound) {
+		return serviceerror.NewHttpError("unable to change wake lease", err.Error(), http.StatusNotFound)
+	}
+	if errors.Is(err, power.ErrReservationExists) {
+		return serviceerror.NewHttpError("unable to reserve cores", err.Error(), http.StatusConflict)
+	}
+	if errors.Is(err, power.ErrInsufficientCores) || errors.Is(err, power.ErrDomainWithoutVcpus) {
+		return serviceerror.NewHttpError("unable to allocate cores", err.Error(), http.StatusConflict)
+	}
+	if errors.Is(err, power.ErrUnknownPool) {
+		return serviceerror.NewHttpError("unable to place domain", err.Error(), http.StatusBadRequest)
+	}
+	if errors.Is(err, power.ErrNoBusySample) {
+		return serviceerror.NewHttpError("busy sampler is not running or has not sampled yet", err.Error(),
+			http.StatusServiceUnavailable)
+	}
+	if errors.Is(err, power.ErrDomainHoldNotFound) {
+		return serviceerror.NewHttpError("unable to release domain", err.Error(), http.StatusNotFound)
+	}
+	if errors.Is(err, power.ErrPlacementNotFound) {
+		return serviceerror.NewHttpError("unable to forget placement", err.Error(), http.StatusNotFound)
+	}
+	if errors.Is(err, power.ErrReservationNotFound) {
+		return serviceerror.NewHttpError("unable to release cores", err.Error(), http.StatusNotFound)
+	}
+	var drainErr *power.DrainError
+	if errors.As(err, &drainErr) {
+		return serviceerror.NewHttpError("dynamic cores are occupied by workloads", drainErr.Error(),
+			http.StatusConflict)
+	}
+	var demandResponseErr *power.DemandResponseError
+	if errors.As(err, &demandResponseErr) {
+		return serviceerror.NewHttpError("pools are shedding load for demand response", demandResponseErr.Error(),
+			http.StatusConflict)
+	}
+	var thermalErr *power.ThermalError
+	if errors.As(err, &thermalErr) {
+		return serviceerror.NewHttpError("cores are too hot to wake", thermalErr.Error(),
+			http.StatusServiceUnavailable)
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	PrepareOperation = "prepare"
+	ReleaseOperation = "release"
+
+	domainHoldsPath = "/gc-controller/v1/domain-holds/"
+	requestTimeout  = 30 * time.Second
+)
+
+// domainXml is the part of the libvirt domain xml which tells the cores the vcpus are pinned to.
+type domainXml struct {
+	Name string `xml:"name"`
+	Vcpu struct {
+		Cpuset string `xml:"cpuset,attr"`
+	} `xml:"vcpu"`
+	Cputune struct {
+		VcpuPins []struct {
+			Cpuset string `xml:"cpuset,attr"`
+		} `xml:"vcpupin"`
+	} `xml:"cputune"`
+}
+
+// RunQemu handles a call of the libvirt qemu hook (/etc/libvirt/hooks/qemu). Upon prepare, the domain xml is read from
+// the given input and the controller listening on the socket wakes dynamic cores the vcpus are pinned to. Upon
+// release, the controller puts them back to sleep. Other operations are ignored.
+func RunQemu(domain string, operation string, input io.Reader, socketPath string) error {
+	switch operation {
+	case PrepareOperation:
+		pinnedCoreIds, err := readPinnedCoreIds(input)
+		if err != nil {
+			return err
+		}
+		if len(pinnedCoreIds) == 0 {
+			return nil
+		}
+		body, err := json.Marshal(model.DomainHoldOp{PinnedCoreIds: pinnedCoreIds})
+		if err != nil {
+			return err
+		}
+		return call(socketPath, http.MethodPut, domain, body)
+	}
+	// libvirt writes the domain xml for every operation, which is drained such that libvirt does not fail writing it.
+	_, _ = io.Copy(io.Discard, input)
+	if operation == ReleaseOperation {
+		return call(socketPath, http.MethodDelete, domain, nil)
+	}
+	return nil
+}
+
+// readPinnedCoreIds lists the cores the vcpus of a domain are pinned to, either all at once via the cpuset of the vcpu
+// element or one by one via cputune.
+func readPinnedCoreIds(input io.Reader) ([]int, error) {
+	var domain domainXml
+	err := xml.NewDecoder(input).Decode(&domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed at parsing domain xml: %w", err)
+	}
+	cpusets := []string{domain.Vcpu.Cpuset}
+	for _, pin := range domain.Cputune.VcpuPins {
+		cpusets = append(cpusets, pin.Cpuset)
+	}
+	var coreIds []int
+	for _, cpuset := range cpusets {
+		ids, err := sysfs.ParseCpuList(cpuset)
+		if err != nil {
+			return nil, fmt.Errorf("failed at parsing vcpu pinning of domain: %s: %w", domain.Name, err)
+		}
+		coreIds = append(coreIds, ids...)
+	}
+	return coreIds, nil
+}
+
+// call sends a request to the controller over its local socket. A release of a domain holding nothing is not an
+// error.
+func call(socketPath string, method string, domain string, body []byte) error {
+	client := http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	req, err := http.NewRequest(method, "http://gc-controller"+domainHoldsPath+url.PathEscape(domain),
+		bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed at calling the controller on socket: %s: %w", socketPath, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusBadRequest ||
+		(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return nil
+	}
+	content, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("controller refused %s of domain: %s: %s: %s", method, domain, resp.Status, content)
+}
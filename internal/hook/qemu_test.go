@@ -0,0 +1,33 @@
+package hook
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadPinnedCoreIds(t *testing.T) {
+	cases := []struct {
+		name      string
+		domainXml string
+		want      []int
+		wantErr   bool
+	}{
+		{name: "unpinned domain", domainXml: `<domain><name>vm</name><vcpu>2</vcpu></domain>`, want: nil},
+		{name: "pinned via vcpu cpuset", domainXml: `<domain><name>vm</name><vcpu cpuset="2-3,^3,6">2</vcpu></domain>`,
+			want: []int{2, 6}},
+		{name: "pinned via cputune", domainXml: `<domain><name>vm</name><vcpu>2</vcpu><cputune>` +
+			`<vcpupin vcpu="0" cpuset="4"/><vcpupin vcpu="1" cpuset="5"/></cputune></domain>`, want: []int{4, 5}},
+		{name: "pinned via both", domainXml: `<domain><name>vm</name><vcpu cpuset="1">2</vcpu><cputune>` +
+			`<vcpupin vcpu="0" cpuset="7"/></cputune></domain>`, want: []int{1, 7}},
+		{name: "malformed cpuset", domainXml: `<domain><name>vm</name><vcpu cpuset="a-b">2</vcpu></domain>`,
+			wantErr: true},
+		{name: "malformed xml", domainXml: `<domain><name>vm</name>`, wantErr: true},
+	}
+	for _, c := range cases {
+		got, err := readPinnedCoreIds(strings.NewReader(c.domainXml))
+		if (err != nil) != c.wantErr || !slices.Equal(got, c.want) {
+			t.Errorf("%s: readPinnedCoreIds() = %v, %v, want %v, an error: %t", c.name, got, err, c.want, c.wantErr)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type HostInfo struct {
 	CPU            string   `json:"cpu"`
 	SleepLevels    []string `json:"sleep-levels"`
@@ -14,10 +16,24 @@ type SleepInfo struct {
 	HostInfo   HostInfo `json:"host-info"`
 }
 
+type IdleState struct {
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	ExitLatencyUs     uint   `json:"exit-latency-us"`
+	TargetResidencyUs uint   `json:"target-residency-us"`
+	PowerMw           uint   `json:"power-mw"`
+}
+
 type Host struct {
-	Name      string `yaml:"name"`
-	Port      int    `yaml:"port"`
-	IsEmulate bool   `yaml:"is-emulate"`
+	Name           string `yaml:"name"`
+	Port           int    `yaml:"port"`
+	IsEmulate      bool   `yaml:"is-emulate"`
+	RuntimeDir     string `yaml:"runtime-dir"`
+	StateDir       string `yaml:"state-dir"`
+	ConflictPolicy string `yaml:"conflict-policy"`
+	PstateMode     string `yaml:"pstate-mode"`
+	Backend        string `yaml:"backend"`
+	IdleGovernor   string `yaml:"idle-governor"`
 }
 
 type Topology struct {
@@ -25,25 +41,154 @@ type Topology struct {
 	DynamicCoreCount int `yaml:"dynamic-core-count"`
 }
 
+type UncoreProfile struct {
+	Package     int `yaml:"package"`
+	PerfMinFrq  int `yaml:"perf-min-frq"`
+	PerfMaxFrq  int `yaml:"perf-max-frq"`
+	SleepMinFrq int `yaml:"sleep-min-frq"`
+	SleepMaxFrq int `yaml:"sleep-max-frq"`
+}
+
 type PowerProfile struct {
-	SleepIdleState string `yaml:"sleep-idle-state"`
-	SleepFrq       int    `yaml:"sleep-frq"`
-	PerfIdleState  string `yaml:"perf-idle-state"`
-	PerfFrq        int    `yaml:"perf-frq"`
+	SleepIdleState          string          `yaml:"sleep-idle-state"`
+	SleepFrq                int             `yaml:"sleep-frq"`
+	PerfIdleState           string          `yaml:"perf-idle-state"`
+	PerfFrq                 int             `yaml:"perf-frq"`
+	SleepMode               string          `yaml:"sleep-mode"`
+	PerfExitLatencyBudgetUs int             `yaml:"perf-exit-latency-budget-us"`
+	StableMaxWakeLatencyUs  int             `yaml:"stable-max-wake-latency-us"`
+	DynamicMaxWakeLatencyUs int             `yaml:"dynamic-max-wake-latency-us"`
+	GlobalMaxWakeLatencyUs  int             `yaml:"global-max-wake-latency-us"`
+	StableTurbo             bool            `yaml:"stable-turbo"`
+	DynamicTurbo            bool            `yaml:"dynamic-turbo"`
+	Uncore                  []UncoreProfile `yaml:"uncore"`
+	UncoreOnSleep           bool            `yaml:"uncore-on-sleep"`
+}
+
+type Verification struct {
+	Enabled         bool `yaml:"enabled"`
+	RetryCount      int  `yaml:"retry-count"`
+	RetryIntervalMs int  `yaml:"retry-interval-ms"`
+}
+
+type Reconciliation struct {
+	Enabled     bool `yaml:"enabled"`
+	IntervalSec int  `yaml:"interval-sec"`
+	AutoCorrect bool `yaml:"auto-correct"`
+}
+
+type Thermal struct {
+	Enabled         bool     `yaml:"enabled"`
+	IntervalSec     int      `yaml:"interval-sec"`
+	Sensors         []string `yaml:"sensors"`
+	CapTempC        int      `yaml:"cap-temp-c"`
+	CapFrq          int      `yaml:"cap-frq"`
+	HysteresisC     int      `yaml:"hysteresis-c"`
+	RefuseWakeTempC int      `yaml:"refuse-wake-temp-c"`
+}
+
+type PowerBudget struct {
+	Enabled         bool `yaml:"enabled"`
+	IntervalSec     int  `yaml:"interval-sec"`
+	PackageWatts    int  `yaml:"package-watts"`
+	HysteresisWatts int  `yaml:"hysteresis-watts"`
+	StepFrq         int  `yaml:"step-frq"`
+	ProgramRapl     bool `yaml:"program-rapl"`
+}
+
+type Autoscaler struct {
+	Enabled      bool `yaml:"enabled"`
+	IntervalSec  int  `yaml:"interval-sec"`
+	HighWaterPct int  `yaml:"high-water-pct"`
+	LowWaterPct  int  `yaml:"low-water-pct"`
+	IdleSec      int  `yaml:"idle-sec"`
+	MinAwakeSec  int  `yaml:"min-awake-sec"`
+	MinAsleepSec int  `yaml:"min-asleep-sec"`
+}
+
+type ScheduleRule struct {
+	Name   string   `yaml:"name" json:"name"`
+	Action string   `yaml:"action" json:"action"`
+	FMhz   uint     `yaml:"f-mhz" json:"f-mhz,omitempty"`
+	Pool   string   `yaml:"pool" json:"pool,omitempty"`
+	Days   []string `yaml:"days" json:"days,omitempty"`
+	Start  string   `yaml:"start" json:"start"`
+	End    string   `yaml:"end" json:"end"`
+	Source string   `yaml:"-" json:"source"`
+}
+
+type Carbon struct {
+	Enabled          bool   `yaml:"enabled"`
+	Source           string `yaml:"source"`
+	IntervalSec      int    `yaml:"interval-sec"`
+	MaxAgeSec        int    `yaml:"max-age-sec"`
+	HighIntensity    int    `yaml:"high-intensity"`
+	LowIntensity     int    `yaml:"low-intensity"`
+	EcoFrq           int    `yaml:"eco-frq"`
+	MinFrq           int    `yaml:"min-frq"`
+	SleepOnHigh      bool   `yaml:"sleep-on-high"`
+	MaxStableUtilPct int    `yaml:"max-stable-util-pct"`
+}
+
+type DemandResponse struct {
+	CapFrq         int      `yaml:"cap-frq"`
+	ProtectedPools []string `yaml:"protected-pools"`
+}
+
+type Leases struct {
+	DefaultTtlSec int `yaml:"default-ttl-sec"`
+	IntervalSec   int `yaml:"interval-sec"`
+}
+
+type Utilization struct {
+	Source     string `yaml:"source"`
+	CgroupRoot string `yaml:"cgroup-root"`
+}
+
+type BusySampler struct {
+	Enabled     bool `yaml:"enabled"`
+	IntervalSec int  `yaml:"interval-sec"`
+}
+
+type Drain struct {
+	Policy     string `yaml:"policy"`
+	Libvirt    bool   `yaml:"libvirt"`
+	CgroupRoot string `yaml:"cgroup-root"`
 }
 
 type ConfYaml struct {
-	Host         Host         `yaml:"host"`
-	Topology     Topology     `yaml:"topology"`
-	PowerProfile PowerProfile `yaml:"power-profile"`
+	Host           Host           `yaml:"host"`
+	Topology       Topology       `yaml:"topology"`
+	PowerProfile   PowerProfile   `yaml:"power-profile"`
+	Verification   Verification   `yaml:"verification"`
+	Reconciliation Reconciliation `yaml:"reconciliation"`
+	Thermal        Thermal        `yaml:"thermal"`
+	PowerBudget    PowerBudget    `yaml:"power-budget"`
+	Autoscaler     Autoscaler     `yaml:"autoscaler"`
+	Schedules      []ScheduleRule `yaml:"schedules"`
+	Carbon         Carbon         `yaml:"carbon"`
+	DemandResponse DemandResponse `yaml:"demand-response"`
+	Leases         Leases         `yaml:"leases"`
+	Drain          Drain          `yaml:"drain"`
+	Utilization    Utilization    `yaml:"utilization"`
+	BusySampler    BusySampler    `yaml:"busy-sampler"`
+}
+
+type CarbonIntensity struct {
+	Intensity float64   `json:"intensity"`
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Policy    string    `json:"policy"`
 }
 
 type GreenScore struct {
-	AwakeStableCores  int `json:"avl-stable-cores"`
-	UtilStableCores   int `json:"util-stable-cores"`
-	AwakeDynamicCores int `json:"avl-dynamic-cores"`
-	UtilDynamicCores  int `json:"util-dynamic-cores"`
-	GreenScore        int `json:"green-score"`
+	AwakeStableCores  int              `json:"avl-stable-cores"`
+	UtilStableCores   int              `json:"util-stable-cores"`
+	AwakeDynamicCores int              `json:"avl-dynamic-cores"`
+	UtilDynamicCores  int              `json:"util-dynamic-cores"`
+	GreenScore        int              `json:"green-score"`
+	CarbonIntensity   *CarbonIntensity `json:"carbon-intensity,omitempty"`
+	Busy              []PoolBusy       `json:"busy,omitempty"`
 }
 
 type PowerStats struct {
@@ -51,3 +196,170 @@ type PowerStats struct {
 	HwUnitType             string  `json:"hw-unit-type"`                    // ex: cpu socket
 	HwUnitPowerConsumption float32 `json:"hw-unit-power-consumption-watts"` // ex: 4.01 Watts
 }
+
+type CoreDiff struct {
+	CoreId    int    `json:"core-id"`
+	Attribute string `json:"attribute"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+}
+
+type DriftEvent struct {
+	Time      time.Time  `json:"time"`
+	Pool      string     `json:"pool"`
+	Diffs     []CoreDiff `json:"diffs"`
+	Corrected bool       `json:"corrected"`
+}
+
+type DriftStats struct {
+	AutoCorrect    bool         `json:"auto-correct"`
+	Checks         int          `json:"checks"`
+	DriftsDetected int          `json:"drifts-detected"`
+	Corrections    int          `json:"corrections"`
+	LastCheck      time.Time    `json:"last-check"`
+	RecentEvents   []DriftEvent `json:"recent-events"`
+}
+
+type ThermalEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Sensor string    `json:"sensor"`
+	TempC  float64   `json:"temp-c"`
+}
+
+type ThermalStats struct {
+	TempC        float64        `json:"temp-c"`
+	Sensor       string         `json:"sensor"`
+	Capped       bool           `json:"capped"`
+	CapFMhz      int            `json:"cap-f-mhz"`
+	LastCheck    time.Time      `json:"last-check"`
+	RecentEvents []ThermalEvent `json:"recent-events"`
+}
+
+type PowerBudgetStats struct {
+	BudgetWatts       int                `json:"budget-watts"`
+	PackageWatts      map[string]float64 `json:"package-watts"`
+	Samples           int                `json:"samples"`
+	SamplesOverBudget int                `json:"samples-over-budget"`
+	CompliancePct     float64            `json:"compliance-pct"`
+	Throttled         bool               `json:"throttled"`
+	SleptByBudget     bool               `json:"slept-by-budget"`
+	LastCheck         time.Time          `json:"last-check"`
+}
+
+type AutoscalerStatus struct {
+	Mode              string    `json:"mode"`
+	StableUtilPct     int       `json:"stable-util-pct"`
+	UtilDynamicCores  int       `json:"util-dynamic-cores"`
+	DynamicAsleep     bool      `json:"dynamic-asleep"`
+	DynamicIdleSince  time.Time `json:"dynamic-idle-since"`
+	LastCheck         time.Time `json:"last-check"`
+	LastTransition    time.Time `json:"last-transition"`
+	LastAction        string    `json:"last-action"`
+	LastActionMessage string    `json:"last-action-message,omitempty"`
+}
+
+type ScheduleOverride struct {
+	Action string    `json:"action"`
+	FMhz   uint      `json:"f-mhz,omitempty"`
+	Pool   string    `json:"pool,omitempty"`
+	Until  time.Time `json:"until"`
+}
+
+type ScheduleStatus struct {
+	Rules         []ScheduleRule    `json:"rules"`
+	Override      *ScheduleOverride `json:"override,omitempty"`
+	ActiveRule    string            `json:"active-rule,omitempty"`
+	AppliedAction string            `json:"applied-action,omitempty"`
+}
+
+type DemandResponseStatus struct {
+	Active         bool      `json:"active"`
+	Level          int       `json:"level"`
+	Until          time.Time `json:"until"`
+	CapFMhz        int       `json:"cap-f-mhz"`
+	ProtectedPools []string  `json:"protected-pools"`
+}
+
+type WakeLease struct {
+	Id      string    `json:"lease-id"`
+	Created time.Time `json:"created"`
+	Expires time.Time `json:"expires"`
+}
+
+type Reservation struct {
+	Owner   string    `json:"owner"`
+	CoreIds []int     `json:"core-ids"`
+	Created time.Time `json:"created"`
+}
+
+type CoreBusy struct {
+	CoreId    int     `json:"core-id"`
+	Pool      string  `json:"pool"`
+	BusyPct   float64 `json:"busy-pct"`
+	IowaitPct float64 `json:"iowait-pct"`
+	StealPct  float64 `json:"steal-pct"`
+}
+
+type PoolBusy struct {
+	Pool      string  `json:"pool"`
+	BusyPct   float64 `json:"busy-pct"`
+	IowaitPct float64 `json:"iowait-pct"`
+	StealPct  float64 `json:"steal-pct"`
+	BusyCores float64 `json:"busy-cores"`
+}
+
+type BusyStats struct {
+	Time        time.Time  `json:"time"`
+	IntervalSec int        `json:"interval-sec"`
+	Pools       []PoolBusy `json:"pools"`
+	Cores       []CoreBusy `json:"cores"`
+}
+
+type WorkloadUtilization struct {
+	Name      string  `json:"name"`
+	Pool      string  `json:"pool"`
+	CoreIds   []int   `json:"core-ids"`
+	UsageUsec uint64  `json:"usage-usec"`
+	BusyPct   float64 `json:"busy-pct"`
+}
+
+type WorkloadStats struct {
+	Source           string                `json:"source"`
+	OccupancyDynamic int                   `json:"occupancy-dynamic"`
+	OccupancyStable  int                   `json:"occupancy-stable"`
+	BusyDynamicPct   float64               `json:"busy-dynamic-pct"`
+	BusyStablePct    float64               `json:"busy-stable-pct"`
+	Workloads        []WorkloadUtilization `json:"workloads"`
+}
+
+type DomainHold struct {
+	Domain  string    `json:"domain"`
+	CoreIds []int     `json:"core-ids"`
+	Created time.Time `json:"created"`
+}
+
+type Placement struct {
+	Domain  string    `json:"domain"`
+	Pool    string    `json:"pool"`
+	CoreIds []int     `json:"core-ids"`
+	Placed  time.Time `json:"placed"`
+}
+
+type Occupant struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	CoreIds []int  `json:"core-ids"`
+}
+
+type DiagnosticCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Remedy string `json:"remedy,omitempty"`
+}
+
+type DiagnosticsReport struct {
+	Passed bool              `json:"passed"`
+	Checks []DiagnosticCheck `json:"checks"`
+}
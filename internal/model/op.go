@@ -3,3 +3,36 @@ package model
 type FqOp struct {
 	FMhz uint `json:"f-mhz"`
 }
+
+type AutoscalerOp struct {
+	Mode string `json:"mode"`
+}
+
+type ScheduleOverrideOp struct {
+	Action      string `json:"action"`
+	FMhz        uint   `json:"f-mhz"`
+	Pool        string `json:"pool"`
+	DurationMin int    `json:"duration-min"`
+}
+
+type DemandResponseOp struct {
+	Level       int `json:"level"`
+	DurationMin int `json:"duration-min"`
+}
+
+type LeaseOp struct {
+	TtlSec int `json:"ttl-sec"`
+}
+
+type ReservationOp struct {
+	Owner     string `json:"owner"`
+	CoreCount int    `json:"core-count"`
+}
+
+type PlacementOp struct {
+	Pool string `json:"pool"`
+}
+
+type DomainHoldOp struct {
+	PinnedCoreIds []int `json:"pinned-core-ids"`
+}
@@ -2,88 +2,268 @@ package power
 
 import (
 	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
 	"log"
+	"slices"
 )
 
-func (o *SleepController) Info() map[string]string {
+func (o *SleepController) Info() map[string]any {
 	fmt.Println("Listing out available sleep states...")
 	if o.isEmulate {
-		return map[string]string{
+		return map[string]any{
 			"message": "Controller is in emulation mode. All api responses are replied with the happy path response",
+			"mode":    o.Mode(),
 		}
 	}
-	log.Printf("avl sleep states: %v\n", o.Host.AvailableCStates())
-	return map[string]string{
-		"avl-idle-states":  fmt.Sprintf("%v", o.Host.AvailableCStates()),
+	log.Printf("avl sleep states: %v\n", o.backend.AvailableIdleStates())
+	idleGovernor, err := readIdleGovernor()
+	if err != nil {
+		idleGovernor = "unknown"
+	}
+	idleStates := []model.IdleState{}
+	if o.features.cStates && len(o.sleepState.stableCpuIds) > 0 {
+		idleStates, err = readIdleStateDetails(o.sleepState.stableCpuIds[0])
+		if err != nil {
+			log.Printf("unable to read idle state details: %v", err)
+		}
+	}
+	return map[string]any{
+		"mode":             o.Mode(),
+		"backend":          o.backend.Name(),
+		"avl-idle-states":  fmt.Sprintf("%v", o.backend.AvailableIdleStates()),
+		"idle-states":      idleStates,
 		"sleep-idle-state": o.conf.PowerProfile.SleepIdleState,
 		"perf-idle-state":  o.conf.PowerProfile.PerfIdleState,
 		"perf-fq":          fmt.Sprintf("%d", o.conf.PowerProfile.PerfFrq),
 		"sleep-fq":         fmt.Sprintf("%d", o.conf.PowerProfile.SleepFrq),
+		"turbo-knob":       o.turbo.knob,
+		"stable-turbo":     fmt.Sprintf("%t", o.conf.PowerProfile.StableTurbo),
+		"dynamic-turbo":    fmt.Sprintf("%t", o.conf.PowerProfile.DynamicTurbo),
+		"uncore-asleep":    fmt.Sprintf("%t", o.uncore.isAsleep),
+		"idle-governor":    idleGovernor,
+
+		"stable-max-wake-latency-us":  fmt.Sprintf("%d", o.conf.PowerProfile.StableMaxWakeLatencyUs),
+		"dynamic-max-wake-latency-us": fmt.Sprintf("%d", o.conf.PowerProfile.DynamicMaxWakeLatencyUs),
+		"global-max-wake-latency-us":  fmt.Sprintf("%d", o.conf.PowerProfile.GlobalMaxWakeLatencyUs),
+		"thermal-cap-fq":              fmt.Sprintf("%d", o.thermal.capFMhz),
 	}
 }
 
 func (o *SleepController) Sleep() error {
-	// dynamic cores went to sleep.
-	o.sleepState.isDynamicCoresAsleep = true
+	return o.SleepInMode("")
+}
 
-	if o.isEmulate {
-		return nil
+// SleepUnlessHeld puts dynamic cores to sleep like Sleep, unless wake leases, domains or an unleased wake hold them
+// awake. Automated policies sleep through it, such that they don't override the holds.
+func (o *SleepController) SleepUnlessHeld() error {
+	return o.sleepInMode("", true)
+}
+
+// SleepInMode puts dynamic cores to sleep. In offline mode, cores are taken offline after applying the sleep
+// profile, otherwise they are only restricted to the sleep idle state and frequency. An empty mode falls back to
+// the configured sleep mode.
+func (o *SleepController) SleepInMode(mode string) error {
+	return o.sleepInMode(mode, false)
+}
+
+func (o *SleepController) sleepInMode(mode string, unlessHeld bool) error {
+	if mode == "" {
+		mode = o.conf.PowerProfile.SleepMode
+	}
+	if mode == "" {
+		mode = IdleSleepMode
+	}
+	if mode != IdleSleepMode && mode != OfflineSleepMode {
+		return fmt.Errorf("unknown sleep mode: %s", mode)
 	}
 
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
 	(*o).mu.Lock()
 	defer (*o).mu.Unlock()
 
-	host := o.Host
+	if unlessHeld && o.isHeldAwake() {
+		return ErrHeldAwake
+	}
+	if o.isEmulate {
+		o.sleepState.isDynamicCoresAsleep = true
+		o.leases.isUnleasedWake = false
+		return nil
+	}
+
+	if len(o.sleepState.offlineCpuIds) > 0 {
+		// offline cores already sleep, and are kept offline while a demand response event sheds them.
+		if mode == OfflineSleepMode || o.isShedOffline() {
+			o.sleepState.isDynamicCoresAsleep = true
+			o.leases.isUnleasedWake = false
+			return nil
+		}
+		err := setCoresOnline(DynamicPool, o.sleepState.offlineCpuIds, true)
+		if err != nil {
+			return err
+		}
+		o.sleepState.offlineCpuIds = nil
+	}
+	if mode == OfflineSleepMode {
+		err := o.checkOfflineSafety(o.sleepState.dynamicCpuIds)
+		if err != nil {
+			return err
+		}
+	}
+
 	//todo need to support per-core sleep state and set it according to the coreCount parameter.
 	// below only set pool sleep state.
-	err2 := setPerf(&host, DynamicPool, uint(o.conf.PowerProfile.SleepFrq))
-	err1 := setPoolSleepState(&host, DynamicPool, o.conf.PowerProfile.SleepIdleState, o.Host.AvailableCStates())
-	if err1 != nil || err2 != nil {
-		//todo handle serviceerror from calling level, and then we can remove below log.
-		return fmt.Errorf("failed at sleeping dynamic pool: %w, %w", err1, err2)
+	profile := o.newPoolProfile(o.conf.PowerProfile.SleepIdleState, uint(o.conf.PowerProfile.SleepFrq))
+	err := o.applyAndVerify(DynamicPool, o.sleepState.dynamicCpuIds, profile, func() error {
+		err := o.applyPoolProfile(DynamicPool, profile)
+		if err != nil {
+			//todo handle serviceerror from calling level, and then we can remove below log.
+			return fmt.Errorf("failed at sleeping dynamic pool: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	o.profiles[DynamicPool] = profile
+
+	if mode == OfflineSleepMode {
+		err = setCoresOnline(DynamicPool, o.sleepState.dynamicCpuIds, false)
+		if err != nil {
+			return err
+		}
+		o.sleepState.offlineCpuIds = slices.Clone(o.sleepState.dynamicCpuIds)
+	}
+	err = o.followSleepWithUncore(true)
+	if err != nil {
+		return fmt.Errorf("failed at lowering uncore frequency: %w", err)
+	}
+	err = o.followSleepWithWakeLatency(true)
+	if err != nil {
+		return fmt.Errorf("failed at lifting wake latency constraint: %w", err)
 	}
+	// dynamic cores went to sleep.
+	o.sleepState.isDynamicCoresAsleep = true
+	o.leases.isUnleasedWake = false
 	log.Printf("dynamic pool sleep state changed to: %s", DeepestSleepStateLbl)
 
 	return nil
 }
 
 func (o *SleepController) Wake() error {
-	o.sleepState.isDynamicCoresAsleep = false
-	if o.isEmulate {
-		return nil
-	}
+	return o.wake(nil)
+}
 
+// wake wakes dynamic cores, and then runs onWoken under the same lock, such that no sleep comes in between.
+func (o *SleepController) wake(onWoken func()) (err error) {
+	err = o.checkWakeTemperature()
+	if err != nil {
+		return err
+	}
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
 	(*o).mu.Lock()
 	defer (*o).mu.Unlock()
+	defer func() {
+		if err == nil && onWoken != nil {
+			onWoken()
+		}
+	}()
+
+	if o.isEmulate {
+		o.sleepState.isDynamicCoresAsleep = false
+		return nil
+	}
+	err = o.checkDemandResponse("waking dynamic pool")
+	if err != nil {
+		return err
+	}
+	err = o.followSleepWithUncore(false)
+	if err != nil {
+		return fmt.Errorf("failed at raising uncore frequency: %w", err)
+	}
+	if len(o.sleepState.offlineCpuIds) > 0 {
+		// cores lose their settings while offline, hence the pool profile below is re-applied.
+		err := setCoresOnline(DynamicPool, o.sleepState.offlineCpuIds, true)
+		if err != nil {
+			return err
+		}
+		o.sleepState.offlineCpuIds = nil
+	}
 
-	host := o.Host
 	//todo need to support per-core sleep state and set it according to the coreCount parameter.
 	// below only set pool sleep state.
-	err2 := setPerf(&host, DynamicPool, uint(o.conf.PowerProfile.PerfFrq))
-	err1 := setPoolSleepState(&host, DynamicPool, o.conf.PowerProfile.PerfIdleState, o.Host.AvailableCStates())
-	if err1 != nil || err2 != nil {
-		//todo handle serviceerror from calling level, and then we can remove below log.
-		return fmt.Errorf("failed at waking dynamic pool: %w, %w", err1, err2)
+	profile := o.newPoolProfile(o.conf.PowerProfile.PerfIdleState, uint(o.conf.PowerProfile.PerfFrq))
+	err = o.applyAndVerify(DynamicPool, o.sleepState.dynamicCpuIds, profile, func() error {
+		err := o.applyPoolProfile(DynamicPool, profile)
+		if err != nil {
+			//todo handle serviceerror from calling level, and then we can remove below log.
+			return fmt.Errorf("failed at waking dynamic pool: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	o.profiles[DynamicPool] = profile
+	err = o.followSleepWithWakeLatency(false)
+	if err != nil {
+		return fmt.Errorf("failed at constraining wake latency: %w", err)
 	}
+	// dynamic cores woke up.
+	o.sleepState.isDynamicCoresAsleep = false
 	log.Println("dynamic pool woken up")
 	return nil
 }
 
 func (o *SleepController) OpFrequency(fMhz uint) error {
+	return o.OpPoolFrequency(DynamicPool, fMhz)
+}
+
+// OpPoolFrequency changes the frequency of the stable or the dynamic pool.
+func (o *SleepController) OpPoolFrequency(poolName string, fMhz uint) error {
+	if poolName != StablePool && poolName != DynamicPool {
+		return fmt.Errorf("frequency of pool: %s can't be changed", poolName)
+	}
 	if o.isEmulate {
 		return nil
 	}
+	if !o.features.freqScaling {
+		return &UnsupportedOperationError{Operation: "changing frequency", Mode: o.Mode()}
+	}
 
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
 	(*o).mu.Lock()
 	defer (*o).mu.Unlock()
 
-	host := o.Host
-	err := setPerf(&host, DynamicPool, fMhz)
+	coreIds := o.sleepState.stableCpuIds
+	if poolName == DynamicPool {
+		if len(o.sleepState.offlineCpuIds) > 0 {
+			return fmt.Errorf("dynamic cores: %v are offline, wake them before changing frequency",
+				o.sleepState.offlineCpuIds)
+		}
+		coreIds = o.sleepState.dynamicCpuIds
+	}
+	err := o.applyAndVerify(poolName, coreIds, poolProfile{fMhz: fMhz}, func() error {
+		err := o.setPerf(poolName, fMhz)
+		if err != nil {
+			//todo handle serviceerror from calling level, and then we can remove below log.
+			log.Print("failed at changing perf frequency: %w", err)
+			return fmt.Errorf("failed at changing perf frequency: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		//todo handle serviceerror from calling level, and then we can remove below log.
-		log.Print("failed at changing perf frequency: %w", err)
-		return fmt.Errorf("failed at changing perf frequency: %w", err)
+		return err
+	}
+	profile := o.profiles[poolName]
+	profile.fMhz = fMhz
+	o.profiles[poolName] = profile
+	if o.thermal.capFMhz > 0 && fMhz > o.thermal.capFMhz {
+		log.Printf("frequency of pool: %s is thermally capped at: %d until the host cools down", poolName,
+			o.thermal.capFMhz)
 	}
-	log.Printf("frequency of pool: %s changed to: %d", DynamicPool, fMhz)
+	log.Printf("frequency of pool: %s changed to: %d", poolName, fMhz)
 	return nil
 }
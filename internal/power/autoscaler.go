@@ -0,0 +1,156 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"log"
+	"time"
+)
+
+const (
+	AutoscalerAutoMode   = "auto"
+	AutoscalerManualMode = "manual"
+
+	autoscalerWake  = "wake"
+	autoscalerSleep = "sleep"
+)
+
+type autoscalerState struct {
+	stop   func()
+	status model.AutoscalerStatus
+}
+
+// StartAutoscaler starts the autoscaler in the configured mode. The loop runs regardless of the mode, and only acts
+// while in auto mode, such that the mode can be switched via the API.
+func (o *SleepController) StartAutoscaler() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.autoscaler.status.Mode = AutoscalerManualMode
+	if o.conf.Autoscaler.Enabled {
+		o.autoscaler.status.Mode = AutoscalerAutoMode
+	}
+	if o.isEmulate || o.autoscaler.stop != nil {
+		return
+	}
+	interval := time.Duration(o.conf.Autoscaler.IntervalSec) * time.Second
+	if interval <= 0 {
+		log.Println("autoscaler interval is not set, sleep and wake are manual only")
+		o.autoscaler.status.Mode = AutoscalerManualMode
+		return
+	}
+
+	log.Printf("starting autoscaler in %s mode with interval: %s", o.autoscaler.status.Mode, interval)
+	o.autoscaler.stop = startLoop(interval, false, o.autoscale)
+}
+
+func (o *SleepController) StopAutoscaler() {
+	o.stopLoop(&o.autoscaler.stop)
+}
+
+// AutoscalerStatus returns the mode of the autoscaler along with its last utilization reading and action.
+func (o *SleepController) AutoscalerStatus() model.AutoscalerStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	status := o.autoscaler.status
+	status.DynamicAsleep = o.sleepState.isDynamicCoresAsleep
+	return status
+}
+
+// SetAutoscalerMode switches between automatic and manual sleep and wake.
+func (o *SleepController) SetAutoscalerMode(mode string) error {
+	if mode != AutoscalerAutoMode && mode != AutoscalerManualMode {
+		return fmt.Errorf("unknown autoscaler mode: %s", mode)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if mode == AutoscalerAutoMode && o.autoscaler.stop == nil && !o.isEmulate {
+		return fmt.Errorf("autoscaler is not running, set autoscaler.interval-sec to enable auto mode")
+	}
+	o.autoscaler.status.Mode = mode
+	o.autoscaler.status.DynamicIdleSince = time.Time{}
+	log.Printf("autoscaler switched to %s mode", mode)
+	return nil
+}
+
+func (o *SleepController) autoscale() {
+	o.mu.Lock()
+	isAuto := o.autoscaler.status.Mode == AutoscalerAutoMode
+	o.mu.Unlock()
+	if !isAuto {
+		return
+	}
+
+	// utilization is read without holding the lock, since it runs third-party clients.
+	utilDynamicCores, utilStableCores, err := getCoreUtilizations(o)
+	if err != nil {
+		log.Printf("autoscaler failed at obtaining core utilization info: %v", err)
+		return
+	}
+
+	o.mu.Lock()
+	action := o.nextAutoscalerAction(utilDynamicCores, utilStableCores)
+	o.mu.Unlock()
+	if action == "" {
+		return
+	}
+
+	if action == autoscalerWake {
+		err = o.Wake()
+	} else {
+		err = o.SleepUnlessHeld()
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.autoscaler.status.LastAction = action
+	o.autoscaler.status.LastActionMessage = ""
+	if err != nil {
+		o.autoscaler.status.LastActionMessage = err.Error()
+		log.Printf("autoscaler failed at %s: %v", action, err)
+		return
+	}
+	o.autoscaler.status.LastTransition = time.Now()
+	o.autoscaler.status.DynamicIdleSince = time.Time{}
+}
+
+// nextAutoscalerAction wakes dynamic cores once stable pool utilization reaches the high-water mark, and puts them to
+// sleep once they have been idle for the configured time while stable pool utilization is below the low-water mark.
+// Either transition is held back until the pool has dwelled in its current state for the configured minimum.
+func (o *SleepController) nextAutoscalerAction(utilDynamicCores int, utilStableCores int) string {
+	conf := o.conf.Autoscaler
+	status := &o.autoscaler.status
+	now := time.Now()
+	status.LastCheck = now
+	status.UtilDynamicCores = utilDynamicCores
+	status.StableUtilPct = 0
+	if len(o.sleepState.stableCpuIds) > 0 {
+		status.StableUtilPct = 100 * utilStableCores / len(o.sleepState.stableCpuIds)
+	}
+	dwelled := now.Sub(status.LastTransition)
+
+	if o.sleepState.isDynamicCoresAsleep {
+		if status.StableUtilPct >= conf.HighWaterPct && dwelled >= time.Duration(conf.MinAsleepSec)*time.Second {
+			log.Printf("stable pool utilization: %d%% reached the high-water mark, waking dynamic pool",
+				status.StableUtilPct)
+			return autoscalerWake
+		}
+		return ""
+	}
+
+	if utilDynamicCores > 0 || status.StableUtilPct >= conf.LowWaterPct {
+		status.DynamicIdleSince = time.Time{}
+		return ""
+	}
+	if status.DynamicIdleSince.IsZero() {
+		status.DynamicIdleSince = now
+	}
+	if now.Sub(status.DynamicIdleSince) >= time.Duration(conf.IdleSec)*time.Second &&
+		dwelled >= time.Duration(conf.MinAwakeSec)*time.Second {
+		log.Printf("dynamic pool idled since: %s, putting it to sleep", status.DynamicIdleSince.Format(time.RFC3339))
+		return autoscalerSleep
+	}
+	return ""
+}
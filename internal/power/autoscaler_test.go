@@ -0,0 +1,52 @@
+package power
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"testing"
+	"time"
+)
+
+func TestNextAutoscalerAction(t *testing.T) {
+	cases := []struct {
+		name             string
+		isAsleep         bool
+		transitionAgo    time.Duration
+		idleSinceAgo     time.Duration
+		utilDynamicCores int
+		utilStableCores  int
+		want             string
+	}{
+		{name: "stable pool at high-water mark wakes", isAsleep: true, transitionAgo: 2 * time.Minute,
+			utilStableCores: 4, want: autoscalerWake},
+		{name: "wake held back until min asleep", isAsleep: true, transitionAgo: 30 * time.Second,
+			utilStableCores: 4},
+		{name: "stable pool below high-water mark keeps sleeping", isAsleep: true, transitionAgo: time.Hour,
+			utilStableCores: 2},
+		{name: "utilized dynamic cores stay awake", transitionAgo: time.Hour, idleSinceAgo: time.Hour,
+			utilDynamicCores: 1},
+		{name: "stable pool above low-water mark keeps dynamic cores awake", transitionAgo: time.Hour,
+			idleSinceAgo: time.Hour, utilStableCores: 3},
+		{name: "idling starts", transitionAgo: time.Hour},
+		{name: "idle dynamic cores sleep", transitionAgo: 700 * time.Second, idleSinceAgo: 400 * time.Second,
+			utilStableCores: 1, want: autoscalerSleep},
+		{name: "sleep held back until min awake", transitionAgo: 500 * time.Second, idleSinceAgo: 400 * time.Second},
+		{name: "sleep held back until idle long enough", transitionAgo: time.Hour, idleSinceAgo: 100 * time.Second},
+	}
+	for _, c := range cases {
+		now := time.Now()
+		o := &SleepController{
+			conf: model.ConfYaml{Autoscaler: model.Autoscaler{HighWaterPct: 80, LowWaterPct: 50, IdleSec: 300,
+				MinAwakeSec: 600, MinAsleepSec: 60}},
+			sleepState: CoreSleeps{stableCpuIds: []int{0, 1, 2, 3}, isDynamicCoresAsleep: c.isAsleep},
+		}
+		o.autoscaler.status.LastTransition = now.Add(-c.transitionAgo)
+		if c.idleSinceAgo > 0 {
+			o.autoscaler.status.DynamicIdleSince = now.Add(-c.idleSinceAgo)
+		}
+		got := o.nextAutoscalerAction(c.utilDynamicCores, c.utilStableCores)
+		if got != c.want {
+			t.Errorf("%s: nextAutoscalerAction(%d, %d) = %q, want %q", c.name, c.utilDynamicCores,
+				c.utilStableCores, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"os"
+	"strings"
+)
+
+const (
+	IntelBackend = "intel"
+	SysfsBackend = "sysfs"
+)
+
+// PowerBackend drives the vendor specific power management of cores. Pools group cores which share the same idle
+// state and frequency limits.
+type PowerBackend interface {
+	Name() string
+	Features() featureMatrix
+	CpuIds() []uint
+
+	AddPool(poolName string, coreIds []uint) error
+	// MoveCores moves cores from their current pool into the given pool.
+	MoveCores(poolName string, coreIds []uint) error
+	AvailableIdleStates() []string
+	SetPoolIdleState(poolName string, idleState string) error
+	SetPoolFrequency(poolName string, minFMhz uint, maxFMhz uint) error
+
+	// Clean hands power management of all pooled cores back to the os.
+	Clean() error
+}
+
+// ResolveBackend returns the name of the configured backend. If none is configured, the intel backend is used for
+// intel processors and the sysfs backend otherwise.
+func ResolveBackend(conf model.Host) string {
+	if conf.Backend != "" {
+		return conf.Backend
+	}
+	if isIntelProcessor() {
+		return IntelBackend
+	}
+	return SysfsBackend
+}
+
+// newPowerBackend creates the backend resolved from the configurations.
+func newPowerBackend(conf model.Host) (PowerBackend, error) {
+	name := ResolveBackend(conf)
+	switch name {
+	case IntelBackend:
+		return newIntelBackend()
+	case SysfsBackend:
+		return newSysfsBackend()
+	default:
+		return nil, fmt.Errorf("unknown power backend: %s", name)
+	}
+}
+
+func isIntelProcessor() bool {
+	cpuInfo, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(cpuInfo), "GenuineIntel")
+}
@@ -0,0 +1,144 @@
+package power
+
+import (
+	"errors"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	ProcStatUtilizationSource     = "procstat"
+	defaultBusySamplerIntervalSec = 5
+)
+
+var ErrNoBusySample = errors.New("busy time is not sampled yet")
+
+type busySamplerState struct {
+	stop        func()
+	intervalSec int
+	lastTimes   map[int]sysfs.CpuTime
+	stats       *model.BusyStats
+}
+
+// StartBusySampler periodically reads the time each core spent busy, in iowait and stolen by the hypervisor from
+// /proc/stat, and derives the percentages per core and per pool over the interval. The sampler also runs when it is
+// the utilization source, even if not enabled.
+func (o *SleepController) StartBusySampler() {
+	isSource := o.utilizationSource() == ProcStatUtilizationSource
+	if o.isEmulate || (!o.conf.BusySampler.Enabled && !isSource) || o.busySampler.stop != nil {
+		return
+	}
+	// the procstat utilization source depends on the sampler, which therefore runs even if the interval is not set.
+	o.busySampler.intervalSec = o.conf.BusySampler.IntervalSec
+	if o.busySampler.intervalSec <= 0 {
+		o.busySampler.intervalSec = defaultBusySamplerIntervalSec
+	}
+	interval := time.Duration(o.busySampler.intervalSec) * time.Second
+
+	log.Printf("starting busy sampler with interval: %s", interval)
+	o.busySampler.stop = startLoop(interval, true, o.sampleBusyTime)
+}
+
+func (o *SleepController) StopBusySampler() {
+	o.stopLoop(&o.busySampler.stop)
+}
+
+// BusyStats returns the busy time of each core and pool over the last interval, or nil if not sampled yet.
+func (o *SleepController) BusyStats() *model.BusyStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.busySampler.stats
+}
+
+func (o *SleepController) sampleBusyTime() {
+	times, err := sysfs.CpuTimes()
+	if err != nil {
+		log.Printf("busy sampler failed at reading cpu times: %v", err)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lastTimes := o.busySampler.lastTimes
+	o.busySampler.lastTimes = times
+	if lastTimes == nil {
+		return
+	}
+	stats := model.BusyStats{Time: time.Now(), IntervalSec: o.busySampler.intervalSec}
+	pools := map[string][]int{
+		StablePool:   o.sleepState.stableCpuIds,
+		DynamicPool:  o.sleepState.dynamicCpuIds,
+		ReservedPool: o.sleepState.reservedCpuIds,
+	}
+	for _, poolName := range []string{StablePool, DynamicPool, ReservedPool} {
+		poolBusy := model.PoolBusy{Pool: poolName}
+		sampledCores := 0
+		for _, coreId := range pools[poolName] {
+			cur, isOnline := times[coreId]
+			prev, wasOnline := lastTimes[coreId]
+			// offline cores are missing in /proc/stat, and counters restart when a core comes back online.
+			if !isOnline || !wasOnline || cur.Total() <= prev.Total() {
+				continue
+			}
+			coreBusy := coreBusyOf(coreId, poolName, prev, cur)
+			stats.Cores = append(stats.Cores, coreBusy)
+			poolBusy.BusyPct += coreBusy.BusyPct
+			poolBusy.IowaitPct += coreBusy.IowaitPct
+			poolBusy.StealPct += coreBusy.StealPct
+			sampledCores++
+		}
+		if len(pools[poolName]) == 0 {
+			continue
+		}
+		poolBusy.BusyCores = poolBusy.BusyPct / 100
+		if sampledCores > 0 {
+			poolBusy.BusyPct /= float64(sampledCores)
+			poolBusy.IowaitPct /= float64(sampledCores)
+			poolBusy.StealPct /= float64(sampledCores)
+		}
+		stats.Pools = append(stats.Pools, poolBusy)
+	}
+	slices.SortFunc(stats.Cores, func(a, b model.CoreBusy) int { return a.CoreId - b.CoreId })
+	o.busySampler.stats = &stats
+}
+
+// coreBusyOf derives the busy, iowait and steal percentages of a core between two readings. Idle, iowait and stolen
+// time are not busy.
+func coreBusyOf(coreId int, poolName string, prev sysfs.CpuTime, cur sysfs.CpuTime) model.CoreBusy {
+	total := float64(cur.Total() - prev.Total())
+	idle := float64(cur.Idle - prev.Idle)
+	iowait := float64(cur.Iowait - prev.Iowait)
+	steal := float64(cur.Steal - prev.Steal)
+	return model.CoreBusy{
+		CoreId:    coreId,
+		Pool:      poolName,
+		BusyPct:   100 * math.Max(0, total-idle-iowait-steal) / total,
+		IowaitPct: 100 * iowait / total,
+		StealPct:  100 * steal / total,
+	}
+}
+
+// getCoreUtilizationFromProcStat counts the busy core equivalents of dynamic (including reserved) and stable cores
+// over the last interval, such that an idle pinned workload does not count as using a core.
+func getCoreUtilizationFromProcStat(o *SleepController) (int, int, error) {
+	stats := o.BusyStats()
+	if stats == nil {
+		return -1, -1, ErrNoBusySample
+	}
+	busyDynamicCores := 0.0
+	busyStableCores := 0.0
+	for _, poolBusy := range stats.Pools {
+		if poolBusy.Pool == StablePool {
+			busyStableCores += poolBusy.BusyCores
+		} else {
+			busyDynamicCores += poolBusy.BusyCores
+		}
+	}
+	return int(math.Round(busyDynamicCores)), int(math.Round(busyStableCores)), nil
+}
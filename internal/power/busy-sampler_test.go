@@ -0,0 +1,32 @@
+package power
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"testing"
+)
+
+func TestCoreBusyOf(t *testing.T) {
+	cases := []struct {
+		name string
+		prev sysfs.CpuTime
+		cur  sysfs.CpuTime
+		want model.CoreBusy
+	}{
+		{name: "busy core", cur: sysfs.CpuTime{User: 60, System: 15, Irq: 5, Idle: 20},
+			want: model.CoreBusy{BusyPct: 80}},
+		{name: "idle core", prev: sysfs.CpuTime{User: 10, Idle: 100}, cur: sysfs.CpuTime{User: 10, Idle: 300}},
+		{name: "iowait and stolen time are not busy", prev: sysfs.CpuTime{Idle: 100},
+			cur:  sysfs.CpuTime{User: 150, Idle: 150, Iowait: 150, Steal: 150},
+			want: model.CoreBusy{BusyPct: 30, IowaitPct: 30, StealPct: 30}},
+		{name: "guest time is part of user time", prev: sysfs.CpuTime{User: 100, Nice: 50, Idle: 50},
+			cur: sysfs.CpuTime{User: 175, Nice: 75, Idle: 150}, want: model.CoreBusy{BusyPct: 50}},
+	}
+	for _, c := range cases {
+		c.want.CoreId, c.want.Pool = 3, DynamicPool
+		got := coreBusyOf(3, DynamicPool, c.prev, c.cur)
+		if got != c.want {
+			t.Errorf("%s: coreBusyOf(%+v, %+v) = %+v, want %+v", c.name, c.prev, c.cur, got, c.want)
+		}
+	}
+}
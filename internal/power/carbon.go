@@ -0,0 +1,243 @@
+package power
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	carbonNormalPolicy = "normal"
+	carbonEcoPolicy    = "eco"
+	carbonSleepPolicy  = "sleep"
+
+	carbonFeedTimeout = 5 * time.Second
+)
+
+type carbonState struct {
+	stop   func()
+	latest *model.CarbonIntensity
+	policy string
+}
+
+// carbonPoint is a single point of the carbon intensity feed. Points without a time are taken as current.
+type carbonPoint struct {
+	Time      time.Time `json:"time"`
+	Intensity float64   `json:"intensity"`
+}
+
+// StartCarbonPolicy periodically reads grid carbon intensity, and lowers the frequency of dynamic cores, or puts them
+// to sleep, while the intensity is high. Dynamic cores are restored once the intensity drops to the low mark.
+func (o *SleepController) StartCarbonPolicy() {
+	o.carbon.policy = carbonNormalPolicy
+	if o.isEmulate || !o.conf.Carbon.Enabled || o.carbon.stop != nil {
+		return
+	}
+	interval := time.Duration(o.conf.Carbon.IntervalSec) * time.Second
+	if interval <= 0 || o.conf.Carbon.Source == "" {
+		log.Println("carbon intensity interval or source is not set, carbon policy is disabled")
+		return
+	}
+	err := checkCarbonSource(o.conf.Carbon.Source)
+	if err != nil {
+		log.Printf("%v, carbon policy is disabled", err)
+		return
+	}
+
+	log.Printf("starting carbon policy with interval: %s, reading intensity from %s", interval, o.conf.Carbon.Source)
+	o.carbon.stop = startLoop(interval, true, o.applyCarbonPolicy)
+}
+
+func (o *SleepController) StopCarbonPolicy() {
+	o.stopLoop(&o.carbon.stop)
+}
+
+// CarbonIntensity returns the last carbon intensity reading along with the policy in effect, or nil if none.
+func (o *SleepController) CarbonIntensity() *model.CarbonIntensity {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.carbon.latest == nil {
+		return nil
+	}
+	latest := *o.carbon.latest
+	latest.Policy = o.carbon.policy
+	return &latest
+}
+
+func (o *SleepController) applyCarbonPolicy() {
+	// the feed and utilization are read without holding the lock, since they do io.
+	maxAge := time.Duration(o.conf.Carbon.MaxAgeSec) * time.Second
+	intensity, err := readCarbonIntensity(o.conf.Carbon.Source, maxAge)
+	if err != nil {
+		log.Printf("failed at reading carbon intensity: %v", err)
+		return
+	}
+	utilDynamicCores, utilStableCores := 0, 0
+	if o.conf.Carbon.SleepOnHigh {
+		utilDynamicCores, utilStableCores, err = getCoreUtilizations(o)
+		if err != nil {
+			log.Printf("carbon policy failed at obtaining core utilization info: %v", err)
+			return
+		}
+	}
+
+	o.mu.Lock()
+	o.carbon.latest = intensity
+	policy, action := o.nextCarbonAction(intensity.Intensity, utilDynamicCores, utilStableCores)
+	o.mu.Unlock()
+
+	if action == nil {
+		return
+	}
+	// the policy only changes once its action succeeds, such that a refused action is retried upon the next reading.
+	err = action()
+	if err != nil {
+		log.Printf("failed at applying carbon policy: %s: %v", policy, err)
+		return
+	}
+	o.mu.Lock()
+	o.carbon.policy = policy
+	o.mu.Unlock()
+}
+
+// nextCarbonAction moves the dynamic pool between the normal, eco and sleep policies, and returns the next policy along
+// with the action applying it, or a nil action if the policy stays. Sleeping is only allowed while dynamic cores are
+// unused and stable pool utilization is within the configured bound, and the eco frequency never goes below the
+// configured minimum.
+func (o *SleepController) nextCarbonAction(intensity float64, utilDynamicCores int,
+	utilStableCores int) (string, func() error) {
+	conf := o.conf.Carbon
+	ecoFMhz := uint(max(conf.EcoFrq, conf.MinFrq))
+	stableUtilPct := 0
+	if len(o.sleepState.stableCpuIds) > 0 {
+		stableUtilPct = 100 * utilStableCores / len(o.sleepState.stableCpuIds)
+	}
+	canSleep := conf.SleepOnHigh && utilDynamicCores == 0 && stableUtilPct < conf.MaxStableUtilPct
+	prevPolicy := o.carbon.policy
+
+	switch {
+	case intensity <= float64(conf.LowIntensity) && prevPolicy != carbonNormalPolicy:
+		log.Printf("carbon intensity: %.1f is low, restoring dynamic pool", intensity)
+		if prevPolicy == carbonSleepPolicy {
+			return carbonNormalPolicy, o.Wake
+		}
+		return carbonNormalPolicy, func() error { return o.OpFrequency(uint(o.conf.PowerProfile.PerfFrq)) }
+	case prevPolicy == carbonSleepPolicy && !canSleep:
+		log.Printf("dynamic cores are needed, waking them at eco frequency: %d MHz", ecoFMhz)
+		return carbonEcoPolicy, func() error {
+			err := o.Wake()
+			if err != nil || !o.features.freqScaling {
+				return err
+			}
+			return o.OpFrequency(ecoFMhz)
+		}
+	case intensity >= float64(conf.HighIntensity) && prevPolicy != carbonSleepPolicy && canSleep:
+		log.Printf("carbon intensity: %.1f is high, putting dynamic pool to sleep", intensity)
+		return carbonSleepPolicy, o.SleepUnlessHeld
+	case intensity >= float64(conf.HighIntensity) && prevPolicy == carbonNormalPolicy && o.features.freqScaling:
+		log.Printf("carbon intensity: %.1f is high, lowering dynamic pool to eco frequency: %d MHz", intensity,
+			ecoFMhz)
+		return carbonEcoPolicy, func() error { return o.OpFrequency(ecoFMhz) }
+	}
+	return prevPolicy, nil
+}
+
+// readCarbonIntensity reads the current carbon intensity from a local json file or http endpoint. The feed is either
+// a single point or a time series, of which the latest point not in the future is used, unless older than maxAge.
+func readCarbonIntensity(source string, maxAge time.Duration) (*model.CarbonIntensity, error) {
+	err := checkCarbonSource(source)
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	if isCarbonFeedUrl(source) {
+		content, err = fetchCarbonFeed(source)
+	} else {
+		content, err = os.ReadFile(source)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed at reading carbon intensity feed: %s: %w", source, err)
+	}
+
+	var points []carbonPoint
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "[") {
+		err = json.Unmarshal(content, &points)
+	} else {
+		var point carbonPoint
+		err = json.Unmarshal(content, &point)
+		points = append(points, point)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed at parsing carbon intensity feed: %s: %w", source, err)
+	}
+
+	now := time.Now()
+	var current *carbonPoint
+	for i, point := range points {
+		if point.Time.IsZero() {
+			points[i].Time = now
+		} else if point.Time.After(now) {
+			continue
+		}
+		if current == nil || !points[i].Time.Before(current.Time) {
+			current = &points[i]
+		}
+	}
+	if current == nil {
+		return nil, fmt.Errorf("carbon intensity feed: %s has no current point", source)
+	}
+	if maxAge > 0 && now.Sub(current.Time) > maxAge {
+		return nil, fmt.Errorf("carbon intensity feed: %s is stale, its latest point is from %s", source,
+			current.Time.Format(time.RFC3339))
+	}
+	return &model.CarbonIntensity{Intensity: current.Intensity, Time: current.Time, Source: source}, nil
+}
+
+func isCarbonFeedUrl(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// checkCarbonSource only accepts files and http endpoints on a loopback address, such that a remote feed can't
+// drive the power state of the host.
+func checkCarbonSource(source string) error {
+	if !isCarbonFeedUrl(source) {
+		return nil
+	}
+	feedUrl, err := url.Parse(source)
+	if err != nil {
+		return fmt.Errorf("invalid carbon intensity source: %s: %w", source, err)
+	}
+	host := feedUrl.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("carbon intensity source: %s must be a file or a loopback address", source)
+	}
+	return nil
+}
+
+func fetchCarbonFeed(feedUrl string) ([]byte, error) {
+	// redirects are not followed, since they may lead off the loopback address.
+	client := http.Client{
+		Timeout: carbonFeedTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(feedUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,120 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNextCarbonAction(t *testing.T) {
+	cases := []struct {
+		name             string
+		prevPolicy       string
+		intensity        float64
+		sleepOnHigh      bool
+		freqScaling      bool
+		utilDynamicCores int
+		utilStableCores  int
+		wantPolicy       string
+		wantsAction      bool
+	}{
+		{name: "high intensity lowers frequency", prevPolicy: carbonNormalPolicy, intensity: 450, freqScaling: true,
+			wantPolicy: carbonEcoPolicy, wantsAction: true},
+		{name: "high intensity sleeps idle dynamic cores", prevPolicy: carbonNormalPolicy, intensity: 450,
+			sleepOnHigh: true, freqScaling: true, utilStableCores: 1, wantPolicy: carbonSleepPolicy, wantsAction: true},
+		{name: "high intensity keeps utilized dynamic cores awake", prevPolicy: carbonNormalPolicy, intensity: 450,
+			sleepOnHigh: true, freqScaling: true, utilDynamicCores: 1, wantPolicy: carbonEcoPolicy, wantsAction: true},
+		{name: "high intensity keeps dynamic cores awake for a busy stable pool", prevPolicy: carbonEcoPolicy,
+			intensity: 450, sleepOnHigh: true, freqScaling: true, utilStableCores: 2, wantPolicy: carbonEcoPolicy},
+		{name: "high intensity without frequency scaling", prevPolicy: carbonNormalPolicy, intensity: 450,
+			wantPolicy: carbonNormalPolicy},
+		{name: "high intensity stays eco", prevPolicy: carbonEcoPolicy, intensity: 450, freqScaling: true,
+			wantPolicy: carbonEcoPolicy},
+		{name: "needed dynamic cores are woken at eco frequency", prevPolicy: carbonSleepPolicy, intensity: 450,
+			sleepOnHigh: true, freqScaling: true, utilDynamicCores: 1, wantPolicy: carbonEcoPolicy, wantsAction: true},
+		{name: "low intensity wakes dynamic cores", prevPolicy: carbonSleepPolicy, intensity: 250, sleepOnHigh: true,
+			freqScaling: true, wantPolicy: carbonNormalPolicy, wantsAction: true},
+		{name: "low intensity restores frequency", prevPolicy: carbonEcoPolicy, intensity: 250, freqScaling: true,
+			wantPolicy: carbonNormalPolicy, wantsAction: true},
+		{name: "intensity between the marks keeps eco", prevPolicy: carbonEcoPolicy, intensity: 350,
+			freqScaling: true, wantPolicy: carbonEcoPolicy},
+		{name: "intensity between the marks keeps normal", prevPolicy: carbonNormalPolicy, intensity: 350,
+			freqScaling: true, wantPolicy: carbonNormalPolicy},
+	}
+	for _, c := range cases {
+		o := &SleepController{
+			conf: model.ConfYaml{Carbon: model.Carbon{HighIntensity: 400, LowIntensity: 300, EcoFrq: 1600,
+				MinFrq: 1200, SleepOnHigh: c.sleepOnHigh, MaxStableUtilPct: 50}},
+			features:   featureMatrix{cStates: true, freqScaling: c.freqScaling},
+			sleepState: CoreSleeps{stableCpuIds: []int{0, 1, 2, 3}},
+			carbon:     carbonState{policy: c.prevPolicy},
+		}
+		policy, action := o.nextCarbonAction(c.intensity, c.utilDynamicCores, c.utilStableCores)
+		if policy != c.wantPolicy || (action != nil) != c.wantsAction {
+			t.Errorf("%s: nextCarbonAction(%.0f) = %s, action: %t, want %s, action: %t", c.name, c.intensity,
+				policy, action != nil, c.wantPolicy, c.wantsAction)
+		}
+	}
+}
+
+func TestReadCarbonIntensity(t *testing.T) {
+	now := time.Now().UTC()
+	point := func(age time.Duration, intensity float64) string {
+		return fmt.Sprintf(`{"time": %q, "intensity": %.0f}`, now.Add(-age).Format(time.RFC3339), intensity)
+	}
+	cases := []struct {
+		name    string
+		feed    string
+		maxAge  time.Duration
+		want    float64
+		wantErr bool
+	}{
+		{name: "point without time is current", feed: `{"intensity": 420}`, maxAge: time.Hour, want: 420},
+		{name: "latest point not in the future", feed: "[" + point(2*time.Hour, 100) + "," +
+			point(10*time.Minute, 200) + "," + point(-time.Hour, 300) + "]", maxAge: time.Hour, want: 200},
+		{name: "stale point", feed: point(3*time.Hour, 100), maxAge: time.Hour, wantErr: true},
+		{name: "point of any age", feed: point(3*time.Hour, 100), want: 100},
+		{name: "only future points", feed: "[" + point(-time.Hour, 300) + "]", maxAge: time.Hour, wantErr: true},
+		{name: "malformed feed", feed: `{"intensity": }`, maxAge: time.Hour, wantErr: true},
+	}
+	for _, c := range cases {
+		source := filepath.Join(t.TempDir(), "carbon-intensity.json")
+		err := os.WriteFile(source, []byte(c.feed), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readCarbonIntensity(source, c.maxAge)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: readCarbonIntensity() = %.0f, want an error", c.name, got.Intensity)
+			}
+			continue
+		}
+		if err != nil || got.Intensity != c.want {
+			t.Errorf("%s: readCarbonIntensity() = %v, %v, want %.0f", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestReadCarbonIntensitySource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"intensity": 420}`))
+	}))
+	defer server.Close()
+
+	got, err := readCarbonIntensity(server.URL, time.Hour)
+	if err != nil || got.Intensity != 420 {
+		t.Errorf("readCarbonIntensity(%s) = %v, %v, want 420", server.URL, got, err)
+	}
+	for _, source := range []string{"http://example.com/carbon-intensity", "https://10.0.0.1/carbon-intensity"} {
+		_, err = readCarbonIntensity(source, time.Hour)
+		if err == nil {
+			t.Errorf("readCarbonIntensity(%s) succeeded, want an error", source)
+		}
+	}
+}
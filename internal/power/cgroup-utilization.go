@@ -0,0 +1,154 @@
+package power
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/workload"
+	"slices"
+	"time"
+)
+
+const (
+	LibvirtUtilizationSource = "libvirt"
+	CgroupUtilizationSource  = "cgroup"
+	// workloadStatsConsumer keeps the cgroup samples of WorkloadStats, of which the busy time is derived.
+	workloadStatsConsumer = "workload-stats"
+)
+
+type utilizationState struct {
+	// cgroupSamples keeps the last samples of each consumer by cgroup path, such that scans of one consumer do not
+	// shorten the interval busy time is derived over for another.
+	cgroupSamples map[string]map[string]cgroupSample
+}
+
+// cgroupSample is the cpu usage of a cgroup at a point in time, of which the busy time is derived on the next scan.
+type cgroupSample struct {
+	usageUsec uint64
+	time      time.Time
+}
+
+// WorkloadStats lists the workloads on stable and dynamic cores. The cgroup source also reports the busy time of each
+// workload and pool since the previous call, while the libvirt source only reports occupancy. Pool busy time is the
+// share of the pool cores the workloads kept busy.
+func (o *SleepController) WorkloadStats() (*model.WorkloadStats, error) {
+	stats := model.WorkloadStats{Source: o.utilizationSource(), Workloads: []model.WorkloadUtilization{}}
+	if stats.Source != CgroupUtilizationSource {
+		utilDynamicCores, utilStableCores, err := getCoreUtilizations(o)
+		if err != nil {
+			return nil, err
+		}
+		stats.OccupancyDynamic, stats.OccupancyStable = utilDynamicCores, utilStableCores
+		return &stats, nil
+	}
+
+	workloads, err := o.scanCgroupWorkloads(workloadStatsConsumer)
+	if err != nil {
+		return nil, err
+	}
+	stats.Workloads = workloads
+	for _, w := range workloads {
+		if w.Pool == DynamicPool {
+			stats.OccupancyDynamic++
+			stats.BusyDynamicPct += w.BusyPct
+		} else {
+			stats.OccupancyStable++
+			stats.BusyStablePct += w.BusyPct
+		}
+	}
+
+	o.mu.Lock()
+	dynamicCoreCount := len(o.sleepState.dynamicCpuIds) + len(o.sleepState.reservedCpuIds)
+	stableCoreCount := len(o.sleepState.stableCpuIds)
+	o.mu.Unlock()
+	// workload busy time is in cores (ex: 100 is one busy core), which is normalized to the size of the pool.
+	if dynamicCoreCount > 0 {
+		stats.BusyDynamicPct /= float64(dynamicCoreCount)
+	}
+	if stableCoreCount > 0 {
+		stats.BusyStablePct /= float64(stableCoreCount)
+	}
+	return &stats, nil
+}
+
+func (o *SleepController) utilizationSource() string {
+	if o.conf.Utilization.Source == "" {
+		return LibvirtUtilizationSource
+	}
+	return o.conf.Utilization.Source
+}
+
+// getCoreUtilizationFromCgroups counts the workloads on dynamic and stable cores.
+func getCoreUtilizationFromCgroups(o *SleepController) (int, int, error) {
+	workloads, err := o.scanCgroupWorkloads("")
+	if err != nil {
+		return -1, -1, err
+	}
+	utilDynamicCores := 0
+	utilStableCores := 0
+	for _, w := range workloads {
+		if w.Pool == DynamicPool {
+			utilDynamicCores++
+		} else {
+			utilStableCores++
+		}
+	}
+	return utilDynamicCores, utilStableCores, nil
+}
+
+// scanCgroupWorkloads attributes each leaf cgroup under the configured root (ex: a container scope) to a pool. Groups
+// confined to dynamic or reserved cores are on the dynamic pool, while the ones which may run on stable cores are on
+// the stable pool, since sleeping dynamic cores does not strand them. Groups on neither are not managed. Busy time is
+// derived since the previous scan of the same consumer, and is not derived if no consumer is given.
+func (o *SleepController) scanCgroupWorkloads(consumer string) ([]model.WorkloadUtilization, error) {
+	// cgroups are walked without holding the lock, since it reads many files.
+	cgroups, err := workload.Cgroups(o.conf.Utilization.CgroupRoot)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	dynamicCpuIds := append(slices.Clone(o.sleepState.dynamicCpuIds), o.sleepState.reservedCpuIds...)
+	samples := map[string]cgroupSample{}
+	var workloads []model.WorkloadUtilization
+	for _, cgroup := range cgroups {
+		if !cgroup.IsLeaf {
+			continue
+		}
+		pool := ""
+		switch {
+		case len(cgroup.EffectiveCpus) == 0:
+		case !slices.ContainsFunc(cgroup.EffectiveCpus, func(id int) bool { return !slices.Contains(dynamicCpuIds, id) }):
+			pool = DynamicPool
+		case slices.ContainsFunc(cgroup.EffectiveCpus, func(id int) bool {
+			return slices.Contains(o.sleepState.stableCpuIds, id)
+		}):
+			pool = StablePool
+		}
+		if pool == "" {
+			continue
+		}
+
+		samples[cgroup.Path] = cgroupSample{usageUsec: cgroup.UsageUsec, time: now}
+		busyPct := 0.0
+		if prev, found := o.utilization.cgroupSamples[consumer][cgroup.Path]; consumer != "" && found && cgroup.UsageUsec >= prev.usageUsec &&
+			now.After(prev.time) {
+			busyPct = 100 * float64(cgroup.UsageUsec-prev.usageUsec) / float64(now.Sub(prev.time).Microseconds())
+		}
+		workloads = append(workloads, model.WorkloadUtilization{
+			Name:      workload.CgroupName(cgroup.Path),
+			Pool:      pool,
+			CoreIds:   cgroup.EffectiveCpus,
+			UsageUsec: cgroup.UsageUsec,
+			BusyPct:   busyPct,
+		})
+	}
+	if consumer != "" {
+		if o.utilization.cgroupSamples == nil {
+			o.utilization.cgroupSamples = map[string]map[string]cgroupSample{}
+		}
+		o.utilization.cgroupSamples[consumer] = samples
+	}
+	return workloads, nil
+}
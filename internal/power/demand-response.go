@@ -0,0 +1,160 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	DemandResponseCapLevel     = 1
+	DemandResponseSleepLevel   = 2
+	DemandResponseOfflineLevel = 3
+)
+
+type demandResponseState struct {
+	// event identifies the latest event, such that the timer of an earlier one doesn't end it.
+	event          uint64
+	level          int
+	until          time.Time
+	timer          *time.Timer
+	capFMhz        uint
+	wasAsleep      bool
+	priorSleepMode string
+}
+
+// DemandResponseError is returned when a request would undo the load shedding of an ongoing demand response event.
+type DemandResponseError struct {
+	Operation string
+	Level     int
+	Until     time.Time
+}
+
+func (e *DemandResponseError) Error() string {
+	return fmt.Sprintf("%s is not allowed during the demand response event of level: %d until %s", e.Operation,
+		e.Level, e.Until.Format(time.RFC3339))
+}
+
+// StartDemandResponse sheds load for the given duration. Level 1 caps the frequency of unprotected pools, level 2
+// also puts dynamic cores to sleep, and level 3 takes them offline. The prior state is restored once the event ends.
+// A new event replaces an ongoing one.
+func (o *SleepController) StartDemandResponse(level int, duration time.Duration) error {
+	if level < DemandResponseCapLevel || level > DemandResponseOfflineLevel {
+		return fmt.Errorf("unknown demand response level: %d", level)
+	}
+	err := o.EndDemandResponse()
+	if err != nil {
+		return fmt.Errorf("failed at ending the ongoing demand response event: %w", err)
+	}
+
+	o.mu.Lock()
+	o.demandResponse.event++
+	event := o.demandResponse.event
+	o.demandResponse.wasAsleep = o.sleepState.isDynamicCoresAsleep
+	o.demandResponse.priorSleepMode = IdleSleepMode
+	if len(o.sleepState.offlineCpuIds) > 0 {
+		o.demandResponse.priorSleepMode = OfflineSleepMode
+	}
+	o.demandResponse.capFMhz = uint(o.conf.DemandResponse.CapFrq)
+	o.reapplyFrequencies()
+	shedDynamicPool := !o.isProtected(DynamicPool) && level >= DemandResponseSleepLevel
+	o.mu.Unlock()
+
+	if shedDynamicPool {
+		mode := IdleSleepMode
+		if level == DemandResponseOfflineLevel {
+			mode = OfflineSleepMode
+		}
+		err = o.SleepInMode(mode)
+		if err != nil {
+			_ = o.EndDemandResponse()
+			return fmt.Errorf("failed at shedding dynamic pool: %w", err)
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.demandResponse.level = level
+	o.demandResponse.until = time.Now().Add(duration)
+	o.demandResponse.timer = time.AfterFunc(duration, func() {
+		err := o.endDemandResponse(event)
+		if err != nil {
+			log.Printf("failed at restoring pools after the demand response event: %v", err)
+		}
+	})
+	log.Printf("demand response event of level: %d started until %s", level,
+		o.demandResponse.until.Format(time.RFC3339))
+	return nil
+}
+
+// EndDemandResponse lifts the frequency cap and brings dynamic cores back to the state they were in before the event.
+func (o *SleepController) EndDemandResponse() error {
+	return o.endDemandResponse(0)
+}
+
+// endDemandResponse ends the ongoing event, or only the given one if not 0, such that an expiring event doesn't end
+// the one which replaced it.
+func (o *SleepController) endDemandResponse(event uint64) error {
+	o.mu.Lock()
+	if event != 0 && event != o.demandResponse.event {
+		o.mu.Unlock()
+		return nil
+	}
+	if o.demandResponse.timer != nil {
+		o.demandResponse.timer.Stop()
+	}
+	wasActive := o.demandResponse.level > 0 || o.demandResponse.capFMhz > 0
+	level, wasAsleep, priorSleepMode := o.demandResponse.level, o.demandResponse.wasAsleep,
+		o.demandResponse.priorSleepMode
+	o.demandResponse = demandResponseState{event: o.demandResponse.event}
+	if wasActive {
+		o.reapplyFrequencies()
+	}
+	o.mu.Unlock()
+
+	if !wasActive || level < DemandResponseSleepLevel || o.isProtected(DynamicPool) {
+		return nil
+	}
+	log.Printf("demand response event of level: %d ended, restoring dynamic pool", level)
+	if !wasAsleep {
+		return o.Wake()
+	}
+	if level == DemandResponseOfflineLevel && priorSleepMode == IdleSleepMode {
+		return o.SleepInMode(IdleSleepMode)
+	}
+	return nil
+}
+
+// DemandResponseStatus returns the ongoing demand response event, if any.
+func (o *SleepController) DemandResponseStatus() model.DemandResponseStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return model.DemandResponseStatus{
+		Active:         o.demandResponse.level > 0,
+		Level:          o.demandResponse.level,
+		Until:          o.demandResponse.until,
+		CapFMhz:        int(o.demandResponse.capFMhz),
+		ProtectedPools: o.conf.DemandResponse.ProtectedPools,
+	}
+}
+
+// isProtected tells whether a pool is latency-critical, and thus exempt from demand response.
+func (o *SleepController) isProtected(poolName string) bool {
+	return slices.Contains(o.conf.DemandResponse.ProtectedPools, poolName)
+}
+
+// checkDemandResponse refuses to wake dynamic cores shed by an ongoing demand response event.
+func (o *SleepController) checkDemandResponse(operation string) error {
+	if o.demandResponse.level < DemandResponseSleepLevel || o.isProtected(DynamicPool) {
+		return nil
+	}
+	return &DemandResponseError{Operation: operation, Level: o.demandResponse.level, Until: o.demandResponse.until}
+}
+
+// isShedOffline tells whether an ongoing demand response event keeps dynamic cores offline.
+func (o *SleepController) isShedOffline() bool {
+	return o.demandResponse.level == DemandResponseOfflineLevel && !o.isProtected(DynamicPool)
+}
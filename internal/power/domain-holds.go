@@ -0,0 +1,103 @@
+package power
+
+import (
+	"errors"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/state"
+	"log"
+	"slices"
+	"time"
+)
+
+const domainHoldsStateName = "domain-holds"
+
+var ErrDomainHoldNotFound = errors.New("domain does not hold dynamic cores")
+
+type domainHoldState struct {
+	holds *state.Records[model.DomainHold]
+}
+
+// StartDomainHolds restores the domains holding dynamic cores awake.
+func (o *SleepController) StartDomainHolds() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.domainHolds.holds = state.NewRecords(state.NewStore(o.conf.Host.StateDir), domainHoldsStateName,
+		func(hold model.DomainHold) string { return hold.Domain },
+		func(hold model.DomainHold) time.Time { return hold.Created })
+	restored, err := o.domainHolds.holds.Load()
+	if err != nil {
+		log.Printf("unable to restore domain holds: %v", err)
+	}
+	if restored > 0 {
+		log.Printf("restored %d domain holds", restored)
+	}
+}
+
+// HoldForDomain wakes dynamic cores for a starting domain pinned to any of them, and keeps them awake until the
+// domain releases them. Domains not pinned to dynamic cores hold nothing, and the returned hold has no cores.
+func (o *SleepController) HoldForDomain(domain string, pinnedCoreIds []int) (*model.DomainHold, error) {
+	o.mu.Lock()
+	var coreIds []int
+	for _, coreId := range pinnedCoreIds {
+		if slices.Contains(o.sleepState.dynamicCpuIds, coreId) && !slices.Contains(coreIds, coreId) {
+			coreIds = append(coreIds, coreId)
+		}
+	}
+	o.mu.Unlock()
+
+	hold := model.DomainHold{Domain: domain, CoreIds: coreIds, Created: time.Now()}
+	if len(coreIds) == 0 {
+		return &hold, nil
+	}
+	var saveErr error
+	err := o.wake(func() {
+		o.domainHolds.holds.Put(hold)
+		saveErr = o.domainHolds.holds.Save()
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("domain: %s holds dynamic cores: %v awake", domain, coreIds)
+	return &hold, saveErr
+}
+
+// ReleaseDomain drops the hold of a stopped domain, and puts dynamic cores to sleep if nothing else holds them.
+func (o *SleepController) ReleaseDomain(domain string) error {
+	o.mu.Lock()
+	if !o.domainHolds.holds.Delete(domain) {
+		o.mu.Unlock()
+		return ErrDomainHoldNotFound
+	}
+	err := o.domainHolds.holds.Save()
+	isLast := !o.isHeldAwake()
+	o.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("domain: %s released dynamic cores", domain)
+	if isLast {
+		log.Println("no domains or wake leases hold dynamic cores, putting dynamic pool to sleep")
+		err = o.SleepUnlessHeld()
+		if errors.Is(err, ErrHeldAwake) {
+			// a domain or a lease came in meanwhile.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// DomainHolds lists the domains holding dynamic cores in the order they started.
+func (o *SleepController) DomainHolds() []model.DomainHold {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.domainHolds.holds.List()
+}
+
+// isHeldAwake tells whether wake leases, domains or an unleased wake keep dynamic cores awake.
+func (o *SleepController) isHeldAwake() bool {
+	return o.leases.leases.Len() > 0 || o.domainHolds.holds.Len() > 0 || o.leases.isUnleasedWake
+}
@@ -0,0 +1,100 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/workload"
+	"log"
+	"slices"
+	"strings"
+)
+
+const (
+	NoDrainPolicy      = "none"
+	RefuseDrainPolicy  = "refuse"
+	MigrateDrainPolicy = "migrate"
+)
+
+// DrainError is returned when workloads are pinned to dynamic cores which are about to sleep, either because the
+// policy refuses to sleep occupied cores, or because they were left behind by a migration.
+type DrainError struct {
+	Occupants []model.Occupant
+	Migrated  bool
+}
+
+func (e *DrainError) Error() string {
+	var occupants []string
+	for _, occupant := range e.Occupants {
+		occupants = append(occupants, fmt.Sprintf("%s: %s on cores: %v", occupant.Kind, occupant.Name,
+			occupant.CoreIds))
+	}
+	if e.Migrated {
+		return fmt.Sprintf("workloads remained on dynamic cores after migration: %s", strings.Join(occupants, ", "))
+	}
+	return fmt.Sprintf("workloads are pinned to dynamic cores: %s", strings.Join(occupants, ", "))
+}
+
+// DrainAndSleep puts dynamic cores to sleep once no workload is pinned to them. As per the drain policy, occupied
+// cores are either refused, or their occupants are re-pinned onto stable cores and the move is verified before
+// sleeping. An empty policy falls back to the configured policy.
+func (o *SleepController) DrainAndSleep(mode string, policy string) error {
+	if o.isEmulate {
+		return o.SleepInMode(mode)
+	}
+	if policy == "" {
+		policy = o.conf.Drain.Policy
+	}
+	if policy == "" || policy == NoDrainPolicy {
+		return o.SleepInMode(mode)
+	}
+	if policy != RefuseDrainPolicy && policy != MigrateDrainPolicy {
+		return fmt.Errorf("unknown drain policy: %s", policy)
+	}
+
+	o.mu.Lock()
+	dynamicCpuIds := slices.Clone(o.sleepState.dynamicCpuIds)
+	stableCpuIds := slices.Clone(o.sleepState.stableCpuIds)
+	o.mu.Unlock()
+
+	// occupants are looked up without holding the lock, since it runs virsh and walks cgroups.
+	occupants, err := o.occupants(dynamicCpuIds)
+	if err != nil {
+		return err
+	}
+	if len(occupants) > 0 && policy == RefuseDrainPolicy {
+		return &DrainError{Occupants: occupants}
+	}
+	if len(occupants) > 0 {
+		for _, occupant := range occupants {
+			log.Printf("migrating %s: %s off dynamic cores: %v", occupant.Kind, occupant.Name, occupant.CoreIds)
+			err = workload.Evict(occupant, dynamicCpuIds, stableCpuIds)
+			if err != nil {
+				return fmt.Errorf("failed at migrating %s: %s: %w", occupant.Kind, occupant.Name, err)
+			}
+		}
+		remaining, err := o.occupants(dynamicCpuIds)
+		if err != nil {
+			return err
+		}
+		if len(remaining) > 0 {
+			return &DrainError{Occupants: remaining, Migrated: true}
+		}
+		var domains []string
+		for _, occupant := range occupants {
+			if occupant.Kind == workload.DomainOccupant {
+				domains = append(domains, occupant.Name)
+			}
+		}
+		o.forgetDynamicPlacements(domains)
+		log.Printf("migrated %d workloads onto stable cores", len(occupants))
+	}
+	return o.SleepInMode(mode)
+}
+
+func (o *SleepController) occupants(coreIds []int) ([]model.Occupant, error) {
+	occupants, err := workload.Occupants(coreIds, o.conf.Drain.Libvirt, o.conf.Drain.CgroupRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed at looking up workloads on dynamic cores: %w", err)
+	}
+	return occupants, nil
+}
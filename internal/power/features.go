@@ -0,0 +1,60 @@
+package power
+
+import (
+	"fmt"
+)
+
+const (
+	FullMode          = "full"
+	FrequencyOnlyMode = "frequency-only"
+	CStateOnlyMode    = "c-state-only"
+	EmulatedMode      = "emulated"
+)
+
+// featureMatrix records which power features the platform supports. The controller degrades to the supported
+// subset instead of refusing to start.
+type featureMatrix struct {
+	cStates     bool
+	freqScaling bool
+}
+
+func (f featureMatrix) mode() string {
+	switch {
+	case f.cStates && f.freqScaling:
+		return FullMode
+	case f.freqScaling:
+		return FrequencyOnlyMode
+	default:
+		return CStateOnlyMode
+	}
+}
+
+// UnsupportedOperationError is returned when an operation needs a power feature the platform does not support.
+type UnsupportedOperationError struct {
+	Operation string
+	Mode      string
+}
+
+func (e *UnsupportedOperationError) Error() string {
+	return fmt.Sprintf("%s is not supported while the controller runs in %s mode", e.Operation, e.Mode)
+}
+
+// Mode returns the operating mode of the controller based on supported power features.
+func (o *SleepController) Mode() string {
+	if o.isEmulate {
+		return EmulatedMode
+	}
+	return o.features.mode()
+}
+
+// newPoolProfile creates a pool profile with only the settings supported by the platform.
+func (o *SleepController) newPoolProfile(idleState string, fMhz uint) poolProfile {
+	profile := poolProfile{}
+	if o.features.cStates {
+		profile.idleState = idleState
+	}
+	if o.features.freqScaling {
+		profile.fMhz = fMhz
+	}
+	return profile
+}
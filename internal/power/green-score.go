@@ -10,12 +10,14 @@ import (
 )
 
 func (o *SleepController) CalculateGreenScore(m *model.GreenScore) error {
+	o.mu.Lock()
 	m.AwakeStableCores = len(o.sleepState.stableCpuIds)
 	if o.sleepState.isDynamicCoresAsleep {
-		m.AwakeDynamicCores = 0
+		m.AwakeDynamicCores = len(o.sleepState.reservedCpuIds)
 	} else {
-		m.AwakeDynamicCores = len(o.sleepState.dynamicCpuIds)
+		m.AwakeDynamicCores = len(o.sleepState.dynamicCpuIds) + len(o.sleepState.reservedCpuIds)
 	}
+	o.mu.Unlock()
 
 	utilDynamicCores, utilStableCores, err := getCoreUtilizations(o)
 	if err != nil {
@@ -30,14 +32,27 @@ func (o *SleepController) CalculateGreenScore(m *model.GreenScore) error {
 	} else {
 		m.GreenScore = 0
 	}
+	m.CarbonIntensity = o.CarbonIntensity()
+	if stats := o.BusyStats(); stats != nil {
+		m.Busy = stats.Pools
+	}
 
 	return nil
 }
 
 func getCoreUtilizations(o *SleepController) (int, int, error) {
-
-	//todo current version obtains utilization from libvirt virtualization. Need to make this configurable and extensible.
-	return getCoreUtilizationFromLibvirt(o.sleepState.dynamicCpuIds, o.sleepState.stableCpuIds)
+	switch o.utilizationSource() {
+	case CgroupUtilizationSource:
+		return getCoreUtilizationFromCgroups(o)
+	case ProcStatUtilizationSource:
+		return getCoreUtilizationFromProcStat(o)
+	}
+	o.mu.Lock()
+	dynamicCpuIds := append(slices.Clone(o.sleepState.dynamicCpuIds), o.sleepState.reservedCpuIds...)
+	stableCpuIds := slices.Clone(o.sleepState.stableCpuIds)
+	o.mu.Unlock()
+	// domains are looked up without holding the lock, since it runs virsh.
+	return getCoreUtilizationFromLibvirt(dynamicCpuIds, stableCpuIds)
 }
 
 type domainsVirshModel struct {
@@ -0,0 +1,117 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"os"
+	"slices"
+)
+
+const (
+	IdleSleepMode    = "idle"
+	OfflineSleepMode = "offline"
+)
+
+// checkOfflineSafety refuses offlining core 0, cores without hotplug support, and the last online cores of a numa
+// node.
+func (o *SleepController) checkOfflineSafety(coreIds []int) error {
+	for _, coreId := range coreIds {
+		if coreId == 0 {
+			return fmt.Errorf("core 0 can not be taken offline")
+		}
+		if _, err := os.Stat(sysfs.CpuFile(coreId, sysfs.OnlineFile)); err != nil {
+			return &UnsupportedOperationError{Operation: fmt.Sprintf("offlining core %d without cpu hotplug", coreId),
+				Mode: o.Mode()}
+		}
+	}
+
+	onlineIds, err := sysfs.OnlineCpuIds()
+	if err != nil {
+		return err
+	}
+	nodes, err := sysfs.NodeCpuIds()
+	if err != nil {
+		return err
+	}
+	for node, nodeCpuIds := range nodes {
+		// nodes without any of the cores are left as is (ex: cpu-less memory nodes of cxl or hbm).
+		if !slices.ContainsFunc(nodeCpuIds, func(id int) bool { return slices.Contains(coreIds, id) }) {
+			continue
+		}
+		remaining := 0
+		for _, id := range nodeCpuIds {
+			if slices.Contains(onlineIds, id) && !slices.Contains(coreIds, id) {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return fmt.Errorf("offlining cores: %v leaves numa node %d without online cores", coreIds, node)
+		}
+	}
+	return nil
+}
+
+// setCoresOnline brings cores online or takes them offline, and reads back the result. Upon a failure, the cores
+// already changed are set back, such that either every core or none is changed.
+func setCoresOnline(poolName string, coreIds []int, online bool) (err error) {
+	value, prevValue := "0", "1"
+	if online {
+		value, prevValue = "1", "0"
+	}
+	var changedIds []int
+	defer func() {
+		if err != nil && len(changedIds) > 0 {
+			restoreCoresOnline(changedIds, prevValue)
+		}
+	}()
+	var diffs []model.CoreDiff
+	for _, coreId := range coreIds {
+		path := sysfs.CpuFile(coreId, sysfs.OnlineFile)
+		err = sysfs.WriteString(path, value)
+		if err != nil {
+			return fmt.Errorf("failed at setting core: %d online status: %w", coreId, err)
+		}
+		var actual string
+		actual, err = sysfs.ReadString(path)
+		if err != nil {
+			// the core is assumed changed, since the write succeeded.
+			changedIds = append(changedIds, coreId)
+			return err
+		}
+		if actual != value {
+			diffs = append(diffs, newCoreDiff(coreId, sysfs.OnlineFile, value, actual))
+			continue
+		}
+		changedIds = append(changedIds, coreId)
+	}
+	if len(diffs) > 0 {
+		return &VerificationError{PoolName: poolName, Diffs: diffs}
+	}
+	log.Printf("cores: %v of pool: %s online status set to: %s", coreIds, poolName, value)
+	return nil
+}
+
+// restoreCoresOnline sets cores back to the given online status, logging the failures since the error which led to
+// the restore is the one reported.
+func restoreCoresOnline(coreIds []int, value string) {
+	for _, coreId := range coreIds {
+		err := sysfs.WriteString(sysfs.CpuFile(coreId, sysfs.OnlineFile), value)
+		if err != nil {
+			log.Printf("unable to set core: %d online status back to: %s: %v", coreId, value, err)
+		}
+	}
+}
+
+// onlineDiffs reports offlined cores which were brought back online behind the controller.
+func onlineDiffs(offlineIds []int) []model.CoreDiff {
+	var diffs []model.CoreDiff
+	for _, coreId := range offlineIds {
+		actual, err := sysfs.ReadString(sysfs.CpuFile(coreId, sysfs.OnlineFile))
+		if err == nil && actual != "0" {
+			diffs = append(diffs, newCoreDiff(coreId, sysfs.OnlineFile, "0", actual))
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,57 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"slices"
+	"strings"
+)
+
+type idleGovernorState struct {
+	orig string
+}
+
+// readIdleGovernor returns the cpuidle governor in use.
+func readIdleGovernor() (string, error) {
+	governor, err := sysfs.ReadString(sysfs.IdleGovernorPath)
+	if err != nil {
+		return sysfs.ReadString(sysfs.IdleGovernorReadOnlyPath)
+	}
+	return governor, nil
+}
+
+// setupIdleGovernor switches the cpuidle governor (ex: menu, teo, ladder, haltpoll) to the configured one.
+func (o *SleepController) setupIdleGovernor() error {
+	governor := o.conf.Host.IdleGovernor
+	available, err := sysfs.ReadString(sysfs.AvailableIdleGovernorsPath)
+	if err != nil {
+		return fmt.Errorf("cpuidle governor can not be changed on this kernel: %w", err)
+	}
+	if !slices.Contains(strings.Fields(available), governor) {
+		return fmt.Errorf("cpuidle governor: %s is not available. available governors: %s", governor, available)
+	}
+	orig, err := readIdleGovernor()
+	if err != nil {
+		return err
+	}
+	err = sysfs.WriteString(sysfs.IdleGovernorPath, governor)
+	if err != nil {
+		return err
+	}
+	o.idleGovernor.orig = orig
+	log.Printf("cpuidle governor changed from %s to %s", orig, governor)
+	return nil
+}
+
+func (o *SleepController) restoreIdleGovernor() error {
+	if o.idleGovernor.orig == "" {
+		return nil
+	}
+	err := sysfs.WriteString(sysfs.IdleGovernorPath, o.idleGovernor.orig)
+	if err != nil {
+		return fmt.Errorf("failed at restoring cpuidle governor: %w", err)
+	}
+	o.idleGovernor.orig = ""
+	return nil
+}
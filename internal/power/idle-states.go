@@ -0,0 +1,51 @@
+package power
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+)
+
+// readIdleStateDetails reads the characteristics of every idle state of a core from cpuidle sysfs.
+func readIdleStateDetails(coreId int) ([]model.IdleState, error) {
+	names, err := sysfs.IdleStateNames(coreId)
+	if err != nil {
+		return nil, err
+	}
+	var states []model.IdleState
+	for i, name := range names {
+		state := model.IdleState{Name: name}
+		state.Description, _ = sysfs.ReadString(sysfs.IdleStateFile(coreId, i, "desc"))
+		state.ExitLatencyUs, err = sysfs.ReadUint(sysfs.IdleStateFile(coreId, i, "latency"))
+		if err != nil {
+			return nil, err
+		}
+		state.TargetResidencyUs, err = sysfs.ReadUint(sysfs.IdleStateFile(coreId, i, "residency"))
+		if err != nil {
+			return nil, err
+		}
+		// power usage is not reported by every idle driver.
+		state.PowerMw, _ = sysfs.ReadUint(sysfs.IdleStateFile(coreId, i, "power"))
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// checkPerfExitLatency warns if waking from the perf idle state takes longer than the configured latency budget.
+func (o *SleepController) checkPerfExitLatency() {
+	budgetUs := o.conf.PowerProfile.PerfExitLatencyBudgetUs
+	if budgetUs <= 0 || len(o.sleepState.stableCpuIds) == 0 {
+		return
+	}
+	states, err := readIdleStateDetails(o.sleepState.stableCpuIds[0])
+	if err != nil {
+		log.Printf("unable to check exit latency of the perf idle state: %v", err)
+		return
+	}
+	for _, state := range states {
+		if state.Name == o.conf.PowerProfile.PerfIdleState && state.ExitLatencyUs > uint(budgetUs) {
+			log.Printf("WARNING: perf idle state: %s has an exit latency of %dus, which exceeds the budget of %dus",
+				state.Name, state.ExitLatencyUs, budgetUs)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package power
 import (
 	"fmt"
 	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
-	"github.com/intel/power-optimization-library/pkg/power"
 	"log"
 	"slices"
 	"sync"
@@ -12,6 +11,7 @@ import (
 const (
 	StablePool                     = "stbl-pool"
 	DynamicPool                    = "dyn-pool"
+	ReservedPool                   = "rsvd-pool"
 	MaxPerformancePowerProfileName = "maxPerfProf"
 )
 
@@ -19,16 +19,47 @@ type CoreSleeps struct {
 	stableCpuIds         []int
 	dynamicCpuIds        []int
 	isDynamicCoresAsleep bool
+	offlineCpuIds        []int
+	// reservedCpuIds are dynamic cores claimed by workloads, which are kept awake in the reserved pool.
+	reservedCpuIds []int
+}
+
+// poolProfile is the power profile a pool is expected to be in. Empty settings are left untouched.
+type poolProfile struct {
+	idleState string
+	fMhz      uint
 }
 
 var DeepestSleepStateLbl string
 
 type SleepController struct {
-	Host       power.Host
-	conf       model.ConfYaml
-	mu         sync.Mutex
-	isEmulate  bool
-	sleepState CoreSleeps
+	// opMu serializes the operations changing pool settings, which release mu while waiting for the settings to
+	// take effect. It is taken before mu.
+	opMu sync.Mutex
+
+	backend        PowerBackend
+	conf           model.ConfYaml
+	mu             sync.Mutex
+	isEmulate      bool
+	sleepState     CoreSleeps
+	profiles       map[string]poolProfile
+	reconciler     reconcilerState
+	features       featureMatrix
+	turbo          turboState
+	uncore         uncoreState
+	idleGovernor   idleGovernorState
+	pmQos          pmQosState
+	thermal        thermalState
+	powerBudget    powerBudgetState
+	autoscaler     autoscalerState
+	carbon         carbonState
+	demandResponse demandResponseState
+	leases         leaseState
+	reservations   reservationState
+	placements     placementState
+	domainHolds    domainHoldState
+	utilization    utilizationState
+	busySampler    busySamplerState
 }
 
 func NewSleepController(conf *model.ConfYaml) (*SleepController, error) {
@@ -44,20 +75,31 @@ func NewSleepController(conf *model.ConfYaml) (*SleepController, error) {
 			dynamicCoreIds = append(dynamicCoreIds, uint(i))
 		}
 		return &SleepController{
-			Host:       nil,
 			conf:       *conf,
 			isEmulate:  true,
 			sleepState: getSleepState(stableCoreIds, dynamicCoreIds),
+			features:   featureMatrix{cStates: true, freqScaling: true},
 		}, nil
 	}
 
 	log.Println("creating a power instance...")
-	host, err := getPowerHost()
+	backend, err := newPowerBackend(conf.Host)
 	if err != nil {
 		return nil, err
 	}
+	features := backend.Features()
+	if !features.cStates && !features.freqScaling {
+		return nil, fmt.Errorf("platform supports neither c-states nor frequency scaling")
+	}
+	controller := &SleepController{
+		backend:   backend,
+		conf:      *conf,
+		isEmulate: false,
+		features:  features,
+	}
+	log.Printf("controller runs in %s mode with the %s backend", controller.Mode(), backend.Name())
 
-	coreIds := host.GetAllCpus().IDs()
+	coreIds := backend.CpuIds()
 	reqCoreCount := conf.Topology.StableCoreCount + conf.Topology.DynamicCoreCount
 	if reqCoreCount > len(coreIds) {
 		return nil, fmt.Errorf("incorrect topology. required core count: %d, but only %d available", reqCoreCount, len(coreIds))
@@ -66,45 +108,92 @@ func NewSleepController(conf *model.ConfYaml) (*SleepController, error) {
 	stableCoreIds := managedCoreIds[0:conf.Topology.StableCoreCount]
 	dynamicCoreIds := managedCoreIds[len(stableCoreIds):reqCoreCount]
 
-	log.Printf("moving cores: %v into the shared pool...", managedCoreIds)
-	err = host.GetSharedPool().SetCpuIDs(managedCoreIds)
-	if err != nil {
-		return nil, fmt.Errorf("failed at moving all cpu cores into the shared pool: %w", err)
-	}
-
 	log.Printf("grouping %v into stable and %v into dynamic pools...", stableCoreIds, dynamicCoreIds)
-	err1 := moveCoresToPool(&host, StablePool, stableCoreIds)
-	err2 := moveCoresToPool(&host, DynamicPool, dynamicCoreIds)
+	err1 := backend.AddPool(StablePool, stableCoreIds)
+	err2 := backend.AddPool(DynamicPool, dynamicCoreIds)
 	if err1 != nil || err2 != nil {
 		return nil, fmt.Errorf("failed at grouping cores into pools: %w and %w", err1, err2)
 	}
+	controller.sleepState = getSleepState(stableCoreIds, dynamicCoreIds)
 
-	log.Println("setting initial perf levels...")
-	err1 = setPerf(&host, StablePool, uint(conf.PowerProfile.PerfFrq))
-	err2 = setPerf(&host, DynamicPool, uint(conf.PowerProfile.PerfFrq))
-	if err1 != nil || err2 != nil {
-		return nil, fmt.Errorf("failed at setting cores to max performance: %w and %w", err1, err2)
+	// host-wide settings changed below are restored if a later step fails, since no controller is returned to clean.
+	isReady := false
+	defer func() {
+		if !isReady {
+			controller.restoreHostSettings()
+		}
+	}()
+
+	if features.freqScaling {
+		log.Println("setting turbo per pool...")
+		err = controller.setupTurbo()
+		if err != nil {
+			return nil, fmt.Errorf("failed at setting turbo: %w", err)
+		}
 	}
 
-	availableIdleStates := host.AvailableCStates()
-	if !slices.Contains(availableIdleStates, conf.PowerProfile.PerfIdleState) || !slices.Contains(availableIdleStates, conf.PowerProfile.SleepIdleState) {
+	availableIdleStates := backend.AvailableIdleStates()
+	if features.cStates && (!slices.Contains(availableIdleStates, conf.PowerProfile.PerfIdleState) ||
+		!slices.Contains(availableIdleStates, conf.PowerProfile.SleepIdleState)) {
 		return nil, fmt.Errorf("platform does not support requested idle states. need %s and %s, "+
 			"but only supports %s", conf.PowerProfile.PerfIdleState, conf.PowerProfile.SleepIdleState, availableIdleStates)
 	}
 
-	log.Println("setting initial sleep levels...")
-	err1 = setPoolSleepState(&host, StablePool, conf.PowerProfile.PerfIdleState, availableIdleStates)
-	err2 = setPoolSleepState(&host, DynamicPool, conf.PowerProfile.PerfIdleState, availableIdleStates)
+	if features.cStates {
+		controller.checkPerfExitLatency()
+	}
+
+	if features.cStates && conf.Host.IdleGovernor != "" {
+		log.Println("setting cpuidle governor...")
+		err = controller.setupIdleGovernor()
+		if err != nil {
+			return nil, fmt.Errorf("failed at setting cpuidle governor: %w", err)
+		}
+	}
+
+	log.Println("setting wake latency constraints...")
+	err = controller.setupWakeLatency()
+	if err != nil {
+		return nil, fmt.Errorf("failed at setting wake latency constraints: %w", err)
+	}
+
+	if len(conf.PowerProfile.Uncore) > 0 {
+		log.Println("setting initial uncore frequencies...")
+		err = controller.setUncore(false)
+		if err != nil {
+			return nil, fmt.Errorf("failed at setting uncore frequencies: %w", err)
+		}
+	}
+
+	log.Println("setting initial perf and sleep levels...")
+	perfProfile := controller.newPoolProfile(conf.PowerProfile.PerfIdleState, uint(conf.PowerProfile.PerfFrq))
+	err1 = controller.applyPoolProfile(StablePool, perfProfile)
+	err2 = controller.applyPoolProfile(DynamicPool, perfProfile)
 	if err1 != nil || err2 != nil {
-		return nil, fmt.Errorf("failed setting pool sleep states: %w, %w", err1, err2)
+		return nil, fmt.Errorf("failed at setting cores to max performance: %w and %w", err1, err2)
 	}
 
-	return &SleepController{
-		Host:       host,
-		conf:       *conf,
-		isEmulate:  false,
-		sleepState: getSleepState(stableCoreIds, dynamicCoreIds),
-	}, nil
+	controller.profiles = map[string]poolProfile{
+		StablePool:  perfProfile,
+		DynamicPool: perfProfile,
+	}
+	isReady = true
+	return controller, nil
+}
+
+// restoreHostSettings reverts the host-wide settings of a controller which failed to start, logging the failures.
+func (o *SleepController) restoreHostSettings() {
+	for name, restore := range map[string]func() error{
+		"turbo":            o.restoreTurbo,
+		"cpuidle governor": o.restoreIdleGovernor,
+		"pm qos":           o.restorePmQos,
+		"uncore":           o.restoreUncore,
+	} {
+		err := restore()
+		if err != nil {
+			log.Printf("unable to restore %s after a failed start: %v", name, err)
+		}
+	}
 }
 
 func getSleepState(stableCoreIds []uint, dynamicCoreIds []uint) CoreSleeps {
@@ -124,75 +213,97 @@ func getSleepState(stableCoreIds []uint, dynamicCoreIds []uint) CoreSleeps {
 	return sleepState
 }
 
+// Clean stops the background loops, waiting for the ones in progress, and restores the host settings changed by the
+// controller.
 func (o *SleepController) Clean() error {
 	if o.isEmulate {
 		return nil
 	}
-	exlPools := o.Host.GetAllExclusivePools()
-	err1 := exlPools.ByName(StablePool).Remove()
-	err2 := exlPools.ByName(DynamicPool).Remove()
-	if err1 != nil || err2 != nil {
-		return fmt.Errorf("failed at moving cores back to the shared pool: %w, %w", err1, err2)
+	o.StopReconciler()
+	o.StopThermalGuard()
+	o.StopPowerBudget()
+	o.StopBusySampler()
+	o.StopAutoscaler()
+	o.StopCarbonPolicy()
+	o.StopLeaseKeeper()
+	o.mu.Lock()
+	if o.demandResponse.timer != nil {
+		o.demandResponse.timer.Stop()
 	}
-	err := o.Host.GetSharedPool().Remove()
-	if err1 != nil || err2 != nil {
-		return fmt.Errorf("failed at moving cores back to the reserved pool: %w", err)
+	// a timer which already fired finds its event replaced, and leaves the host as is.
+	o.demandResponse.event++
+	o.mu.Unlock()
+	err := o.restoreOfflineCores()
+	if err != nil {
+		return err
 	}
-	return nil
+	err1 := o.restoreTurbo()
+	err2 := o.restoreUncore()
+	err3 := o.restoreIdleGovernor()
+	err4 := o.restorePmQos()
+	err5 := o.restoreRaplLimits()
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil || err5 != nil {
+		return fmt.Errorf("failed at restoring system-wide power settings: %w, %w, %w, %w, %w", err1, err2, err3,
+			err4, err5)
+	}
+	return o.backend.Clean()
 }
 
-func setPoolSleepState(host *power.Host, poolName string, idleState string, avlIdleStates []string) error {
-	cStates := power.CStates{}
-	for _, state := range avlIdleStates {
-		if state == idleState {
-			cStates[state] = true
-			continue
+// restoreOfflineCores brings the cores taken offline back online.
+func (o *SleepController) restoreOfflineCores() error {
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.sleepState.offlineCpuIds) > 0 {
+		err := setCoresOnline(DynamicPool, o.sleepState.offlineCpuIds, true)
+		if err != nil {
+			return fmt.Errorf("failed at bringing offline cores back online: %w", err)
 		}
-		cStates[state] = false
-	}
-	log.Printf("setting pool: %s sleep levels as %v ...", poolName, cStates)
-	err := (*host).GetExclusivePool(poolName).SetCStates(cStates)
-	if err != nil {
-		return fmt.Errorf("failed at setting %s pool to %v", poolName, cStates)
+		o.sleepState.offlineCpuIds = nil
 	}
 	return nil
 }
 
-func setPerf(host *power.Host, poolName string, baseFMhz uint) error {
-	maxFMhz := baseFMhz + 100
-	maxPerfProf, err := power.NewPowerProfile(MaxPerformancePowerProfileName, baseFMhz, maxFMhz, "performance", "performance")
-	if err != nil {
-		return fmt.Errorf("failed at creating a power profile: %w", err)
+// applyPoolProfile sets the idle state and frequency of a pool, skipping settings absent in the profile.
+func (o *SleepController) applyPoolProfile(poolName string, profile poolProfile) error {
+	var err1, err2 error
+	if profile.fMhz > 0 {
+		err1 = o.setPerf(poolName, profile.fMhz)
 	}
-	err = (*host).GetExclusivePool(poolName).SetPowerProfile(maxPerfProf)
-	if err != nil {
-		return fmt.Errorf("failed at setting %s pool to max power profile: %w", poolName, err)
+	if profile.idleState != "" {
+		err2 = o.backend.SetPoolIdleState(poolName, profile.idleState)
+	}
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("failed at applying profile of pool: %s: %w, %w", poolName, err1, err2)
 	}
 	return nil
 }
 
-func getPowerHost() (power.Host, error) {
-	host, allErrors := power.CreateInstance("gc-enabled-host")
-	if host != nil {
-		features := host.GetFeaturesInfo()
-		var reqPowerOptmzFeatures error
-		reqPowerOptmzFeatures = features[power.CStatesFeature].FeatureError()
-		reqPowerOptmzFeatures = features[power.FreqencyScalingFeature].FeatureError()
-		if reqPowerOptmzFeatures != nil {
-			return nil, fmt.Errorf("failed at creating a power instance: %w", allErrors)
-		}
-	}
-	return host, nil
+func (o *SleepController) setPerf(poolName string, baseFMhz uint) error {
+	minFMhz, maxFMhz := o.frequencyRange(poolName, baseFMhz)
+	return o.backend.SetPoolFrequency(poolName, minFMhz, maxFMhz)
 }
 
-func moveCoresToPool(host *power.Host, poolName string, coreIDs []uint) error {
-	gcPool, err := (*host).AddExclusivePool(poolName)
-	if err != nil {
-		return fmt.Errorf("failed at creating exclusive pool for %s: %w", poolName, err)
+// frequencyRange returns the min and max frequencies a pool is limited to for the given base frequency. Pools
+// disallowed to turbo are capped below the turbo range, all pools are capped while the host runs hot, and
+// unprotected pools are capped during demand response events.
+func (o *SleepController) frequencyRange(poolName string, baseFMhz uint) (uint, uint) {
+	minFMhz, maxFMhz := baseFMhz, baseFMhz+100
+	turboPoolName := poolName
+	if poolName == ReservedPool {
+		// reserved cores come from the dynamic pool, and keep its turbo setting.
+		turboPoolName = DynamicPool
 	}
-	err = gcPool.MoveCpuIDs(coreIDs)
-	if err != nil {
-		return fmt.Errorf("failed at moving cpu core to the %s pool: %w", poolName, err)
+	if capFMhz, capped := o.turbo.capFMhz[turboPoolName]; capped {
+		minFMhz, maxFMhz = min(minFMhz, capFMhz), min(maxFMhz, capFMhz)
 	}
-	return nil
+	if o.thermal.capFMhz > 0 {
+		minFMhz, maxFMhz = min(minFMhz, o.thermal.capFMhz), min(maxFMhz, o.thermal.capFMhz)
+	}
+	if o.demandResponse.capFMhz > 0 && !o.isProtected(poolName) {
+		minFMhz, maxFMhz = min(minFMhz, o.demandResponse.capFMhz), min(maxFMhz, o.demandResponse.capFMhz)
+	}
+	return minFMhz, maxFMhz
 }
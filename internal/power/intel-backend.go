@@ -0,0 +1,129 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+	"github.com/intel/power-optimization-library/pkg/power"
+	"log"
+)
+
+// intelBackend manages cores through the Intel Power Optimization Library.
+type intelBackend struct {
+	host     power.Host
+	features featureMatrix
+	pools    []string
+}
+
+func newIntelBackend() (*intelBackend, error) {
+	host, allErrors := power.CreateInstance("gc-enabled-host")
+	if host == nil {
+		return nil, fmt.Errorf("failed at creating a power instance: %w", allErrors)
+	}
+	features := host.GetFeaturesInfo()
+	cStatesErr := features[power.CStatesFeature].FeatureError()
+	freqScalingErr := features[power.FreqencyScalingFeature].FeatureError()
+	if cStatesErr != nil {
+		log.Printf("c-states are not supported, idle states will not be managed: %v", cStatesErr)
+	}
+	if freqScalingErr != nil {
+		log.Printf("frequency scaling is not supported, frequencies will not be managed: %v", freqScalingErr)
+	}
+	return &intelBackend{
+		host:     host,
+		features: featureMatrix{cStates: cStatesErr == nil, freqScaling: freqScalingErr == nil},
+	}, nil
+}
+
+func (b *intelBackend) Name() string {
+	return IntelBackend
+}
+
+func (b *intelBackend) Features() featureMatrix {
+	return b.features
+}
+
+func (b *intelBackend) CpuIds() []uint {
+	return b.host.GetAllCpus().IDs()
+}
+
+func (b *intelBackend) AddPool(poolName string, coreIds []uint) error {
+	err := b.host.GetSharedPool().MoveCpuIDs(coreIds)
+	if err != nil {
+		return fmt.Errorf("failed at moving cpu cores into the shared pool: %w", err)
+	}
+	gcPool, err := b.host.AddExclusivePool(poolName)
+	if err != nil {
+		return fmt.Errorf("failed at creating exclusive pool for %s: %w", poolName, err)
+	}
+	err = gcPool.MoveCpuIDs(coreIds)
+	if err != nil {
+		return fmt.Errorf("failed at moving cpu core to the %s pool: %w", poolName, err)
+	}
+	b.pools = append(b.pools, poolName)
+	return nil
+}
+
+func (b *intelBackend) MoveCores(poolName string, coreIds []uint) error {
+	// the library does not move cores across exclusive pools, hence they pass through the shared pool.
+	err := b.host.GetSharedPool().MoveCpuIDs(coreIds)
+	if err != nil {
+		return fmt.Errorf("failed at moving cpu cores into the shared pool: %w", err)
+	}
+	err = b.host.GetExclusivePool(poolName).MoveCpuIDs(coreIds)
+	if err != nil {
+		return fmt.Errorf("failed at moving cpu cores to the %s pool: %w", poolName, err)
+	}
+	return nil
+}
+
+func (b *intelBackend) AvailableIdleStates() []string {
+	return b.host.AvailableCStates()
+}
+
+func (b *intelBackend) SetPoolIdleState(poolName string, idleState string) error {
+	cStates := power.CStates{}
+	for _, state := range b.host.AvailableCStates() {
+		if state == idleState {
+			cStates[state] = true
+			continue
+		}
+		cStates[state] = false
+	}
+	log.Printf("setting pool: %s sleep levels as %v ...", poolName, cStates)
+	err := b.host.GetExclusivePool(poolName).SetCStates(cStates)
+	if err != nil {
+		return fmt.Errorf("failed at setting %s pool to %v", poolName, cStates)
+	}
+	return nil
+}
+
+func (b *intelBackend) SetPoolFrequency(poolName string, minFMhz uint, maxFMhz uint) error {
+	maxPerfProf, err := power.NewPowerProfile(MaxPerformancePowerProfileName, minFMhz, maxFMhz, "performance", "performance")
+	if err != nil {
+		return fmt.Errorf("failed at creating a power profile: %w", err)
+	}
+	err = b.host.GetExclusivePool(poolName).SetPowerProfile(maxPerfProf)
+	if err != nil {
+		return fmt.Errorf("failed at setting %s pool to max power profile: %w", poolName, err)
+	}
+	return nil
+}
+
+func (b *intelBackend) Clean() error {
+	exlPools := b.host.GetAllExclusivePools()
+	var errs []error
+	for _, poolName := range b.pools {
+		err := exlPools.ByName(poolName).Remove()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed at moving cores back to the shared pool: %w", errors.Join(errs...))
+	}
+	err := b.host.GetSharedPool().Remove()
+	if err != nil {
+		return fmt.Errorf("failed at moving cores back to the reserved pool: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package power
+
+import (
+	"errors"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/state"
+	"github.com/google/uuid"
+	"log"
+	"time"
+)
+
+const (
+	leasesStateName         = "wake-leases"
+	defaultLeaseIntervalSec = 5
+)
+
+var (
+	ErrLeaseNotFound = errors.New("wake lease not found")
+	ErrHeldAwake     = errors.New("dynamic pool is held awake by wake leases or domains")
+)
+
+type leaseState struct {
+	stop   func()
+	leases *state.Records[model.WakeLease]
+	// isUnleasedWake tells whether dynamic cores were last woken without a lease, which keeps them awake until an
+	// explicit sleep even if leases are released or expire.
+	isUnleasedWake bool
+}
+
+// StartLeaseKeeper restores persisted wake leases, and periodically puts dynamic cores back to sleep once all of
+// their leases expire.
+func (o *SleepController) StartLeaseKeeper() {
+	if o.leases.stop != nil {
+		return
+	}
+	o.leases.leases = state.NewRecords(state.NewStore(o.conf.Host.StateDir), leasesStateName,
+		func(lease model.WakeLease) string { return lease.Id },
+		func(lease model.WakeLease) time.Time { return lease.Created })
+	restored, err := o.leases.leases.Load()
+	if err != nil {
+		log.Printf("unable to restore wake leases: %v", err)
+	}
+	if restored > 0 {
+		log.Printf("restored %d wake leases, keeping dynamic pool awake until they expire", restored)
+		err = o.Wake()
+		if err != nil {
+			log.Printf("unable to wake dynamic pool for restored leases: %v", err)
+		}
+	}
+
+	interval := time.Duration(o.conf.Leases.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = defaultLeaseIntervalSec * time.Second
+	}
+	o.leases.stop = startLoop(interval, false, o.expireLeases)
+}
+
+func (o *SleepController) StopLeaseKeeper() {
+	o.stopLoop(&o.leases.stop)
+}
+
+// DefaultLeaseTtlSec returns the ttl of wake requests which do not specify one, or zero if such wakes are not leased.
+func (o *SleepController) DefaultLeaseTtlSec() int {
+	return o.conf.Leases.DefaultTtlSec
+}
+
+// WakeWithLease wakes dynamic cores and keeps them awake until the returned lease expires or is released.
+// The lease is granted under the same lock as the wake, such that expiring leases don't put the cores back to sleep
+// in between.
+func (o *SleepController) WakeWithLease(ttl time.Duration) (*model.WakeLease, error) {
+	var lease model.WakeLease
+	var saveErr error
+	err := o.wake(func() {
+		o.leases.isUnleasedWake = false
+		now := time.Now()
+		lease = model.WakeLease{
+			Id:      uuid.New().String(),
+			Created: now,
+			Expires: now.Add(ttl),
+		}
+		o.leases.leases.Put(lease)
+		saveErr = o.leases.leases.Save()
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("wake lease: %s granted until %s", lease.Id, lease.Expires.Format(time.RFC3339))
+	return &lease, saveErr
+}
+
+// WakeWithoutLease wakes dynamic cores and keeps them awake until they are put to sleep, regardless of the leases
+// released or expired meanwhile.
+func (o *SleepController) WakeWithoutLease() error {
+	return o.wake(func() {
+		o.leases.isUnleasedWake = true
+	})
+}
+
+// RenewLease extends a lease to expire after the given ttl from now.
+func (o *SleepController) RenewLease(id string, ttl time.Duration) (*model.WakeLease, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lease, found := o.leases.leases.Get(id)
+	if !found {
+		return nil, ErrLeaseNotFound
+	}
+	lease.Expires = time.Now().Add(ttl)
+	o.leases.leases.Put(lease)
+	return &lease, o.leases.leases.Save()
+}
+
+// ReleaseLease drops a lease, and puts dynamic cores to sleep if it was the last one.
+func (o *SleepController) ReleaseLease(id string) error {
+	o.mu.Lock()
+	if !o.leases.leases.Delete(id) {
+		o.mu.Unlock()
+		return ErrLeaseNotFound
+	}
+	err := o.leases.leases.Save()
+	isLast := !o.isHeldAwake()
+	o.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("wake lease: %s released", id)
+	if isLast {
+		log.Println("no wake leases or domains left, putting dynamic pool to sleep")
+		err = o.SleepUnlessHeld()
+		if errors.Is(err, ErrHeldAwake) {
+			// a lease or a domain came in meanwhile.
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Leases lists the wake leases in the order they were granted.
+func (o *SleepController) Leases() []model.WakeLease {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.leases.leases.List()
+}
+
+func (o *SleepController) expireLeases() {
+	o.mu.Lock()
+	now := time.Now()
+	expired := 0
+	for _, lease := range o.leases.leases.List() {
+		if now.After(lease.Expires) {
+			log.Printf("wake lease: %s expired", lease.Id)
+			o.leases.leases.Delete(lease.Id)
+			expired++
+		}
+	}
+	if expired == 0 {
+		o.mu.Unlock()
+		return
+	}
+	err := o.leases.leases.Save()
+	if err != nil {
+		log.Printf("unable to persist wake leases: %v", err)
+	}
+	isLast := !o.isHeldAwake()
+	o.mu.Unlock()
+
+	if isLast {
+		log.Println("all wake leases expired and no domains hold dynamic cores, putting dynamic pool to sleep")
+		err = o.SleepUnlessHeld()
+		if err != nil && !errors.Is(err, ErrHeldAwake) {
+			log.Printf("failed at putting dynamic pool to sleep: %v", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package power
+
+import "time"
+
+// startLoop calls fn every interval in a goroutine, and right away as well if runNow is set, until the returned stop
+// function is called. Stopping waits for a call in progress to return.
+func startLoop(interval time.Duration, runNow bool, fn func()) (stop func()) {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		if runNow {
+			fn()
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// stopLoop stops the loop of the given stop function field, if running. The lock is not held while waiting for the
+// loop to exit, since loop calls take it.
+func (o *SleepController) stopLoop(stop *func()) {
+	o.mu.Lock()
+	stopFn := *stop
+	*stop = nil
+	o.mu.Unlock()
+	if stopFn != nil {
+		stopFn()
+	}
+}
@@ -0,0 +1,198 @@
+package power
+
+import (
+	"errors"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/state"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/workload"
+	"log"
+	"time"
+)
+
+const (
+	placementsStateName = "placements"
+	emulatedDomainVcpus = 1
+)
+
+var (
+	ErrUnknownPool        = errors.New("domains can only be placed onto the stable or the dynamic pool")
+	ErrPlacementNotFound  = errors.New("placement not found")
+	ErrDomainWithoutVcpus = errors.New("domain has no vcpus to pin")
+)
+
+type placementState struct {
+	placements *state.Records[model.Placement]
+}
+
+// StartPlacements restores the recorded placements of libvirt domains.
+func (o *SleepController) StartPlacements() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.placements.placements = state.NewRecords(state.NewStore(o.conf.Host.StateDir), placementsStateName,
+		func(placement model.Placement) string { return placement.Domain },
+		func(placement model.Placement) time.Time { return placement.Placed })
+	restored, err := o.placements.placements.Load()
+	if err != nil {
+		log.Printf("unable to restore placements: %v", err)
+	}
+	if restored > 0 {
+		log.Printf("restored %d placements", restored)
+	}
+}
+
+// Place pins each vcpu of a running libvirt domain to a free core of the given pool, and its emulator thread to those
+// cores. Cores pinned by other domains or placements are not free. A placed domain is re-pinned upon placing it again,
+// and keeps its previous pinning if pinning fails. In emulation, domains have a single vcpu and are not pinned.
+func (o *SleepController) Place(domain string, poolName string) (*model.Placement, error) {
+	if poolName != StablePool && poolName != DynamicPool {
+		return nil, ErrUnknownPool
+	}
+	vcpuCount := emulatedDomainVcpus
+	var prevPinning *workload.DomainPinning
+	var busyCoreIds []int
+	if !o.isEmulate {
+		// domains are looked up and pinned without holding the lock, since it runs virsh.
+		var err error
+		prevPinning, err = workload.GetDomainPinning(domain)
+		if err != nil {
+			return nil, err
+		}
+		vcpuCount = len(prevPinning.Vcpus)
+		busyCoreIds, err = pinnedCoreIds(domain)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if vcpuCount == 0 {
+		return nil, ErrDomainWithoutVcpus
+	}
+
+	o.mu.Lock()
+	for _, placement := range o.placements.placements.List() {
+		if placement.Domain != domain {
+			busyCoreIds = append(busyCoreIds, placement.CoreIds...)
+		}
+	}
+	poolCpuIds := o.sleepState.stableCpuIds
+	if poolName == DynamicPool {
+		poolCpuIds = o.sleepState.dynamicCpuIds
+	}
+	freeCoreIds := withoutIds(poolCpuIds, busyCoreIds)
+	if len(freeCoreIds) < vcpuCount {
+		o.mu.Unlock()
+		return nil, ErrInsufficientCores
+	}
+	coreIds := freeCoreIds[:vcpuCount]
+	// cores are claimed before pinning, such that placements made while pinning do not pick them as well.
+	prevPlacement, wasPlaced := o.placements.placements.Get(domain)
+	placement := model.Placement{Domain: domain, Pool: poolName, CoreIds: coreIds, Placed: time.Now()}
+	o.placements.placements.Put(placement)
+	isAsleep := poolName == DynamicPool && o.sleepState.isDynamicCoresAsleep
+	o.mu.Unlock()
+
+	if !o.isEmulate {
+		// vcpus are numbered from 0, and each gets a core of its own.
+		pinning := workload.DomainPinning{Domain: domain, Vcpus: map[int][]int{}, Emulator: coreIds}
+		for vcpu, coreId := range coreIds {
+			pinning.Vcpus[vcpu] = []int{coreId}
+		}
+		err := workload.SetDomainPinning(&pinning)
+		if err != nil {
+			restoreErr := workload.SetDomainPinning(prevPinning)
+			if restoreErr != nil {
+				log.Printf("unable to restore the pinning of domain: %s: %v", domain, restoreErr)
+			}
+			o.mu.Lock()
+			defer o.mu.Unlock()
+			// the record is left as is if the domain was placed again meanwhile.
+			if current, _ := o.placements.placements.Get(domain); !current.Placed.Equal(placement.Placed) {
+				return nil, err
+			}
+			if wasPlaced {
+				o.placements.placements.Put(prevPlacement)
+			} else {
+				o.placements.placements.Delete(domain)
+			}
+			return nil, err
+		}
+	}
+	if isAsleep {
+		log.Printf("domain: %s is placed onto sleeping dynamic cores", domain)
+	}
+	log.Printf("domain: %s placed onto %s cores: %v", domain, poolName, coreIds)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return &placement, o.placements.placements.Save()
+}
+
+// ForgetPlacement drops the record of a placement, which frees its cores for other placements. The pinning of the
+// domain is left as is.
+func (o *SleepController) ForgetPlacement(domain string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.placements.placements.Delete(domain) {
+		return ErrPlacementNotFound
+	}
+	return o.placements.placements.Save()
+}
+
+// Placements lists the placements in the order they were made.
+func (o *SleepController) Placements() []model.Placement {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.placements.placements.List()
+}
+
+// forgetDynamicPlacements drops the dynamic pool placements of the given domains, once they are migrated off the
+// dynamic cores.
+func (o *SleepController) forgetDynamicPlacements(domains []string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	forgotten := 0
+	for _, domain := range domains {
+		if placement, found := o.placements.placements.Get(domain); found && placement.Pool == DynamicPool {
+			o.placements.placements.Delete(domain)
+			forgotten++
+		}
+	}
+	if forgotten == 0 {
+		return
+	}
+	err := o.placements.placements.Save()
+	if err != nil {
+		log.Printf("unable to persist placements: %v", err)
+	}
+}
+
+// pinnedCoreIds lists the cores pinned by running domains other than the given one. Domains which may run on any
+// online core are not pinned.
+func pinnedCoreIds(excludedDomain string) ([]int, error) {
+	onlineIds, err := sysfs.OnlineCpuIds()
+	if err != nil {
+		return nil, err
+	}
+	domains, err := workload.Domains()
+	if err != nil {
+		return nil, err
+	}
+	var coreIds []int
+	for _, domain := range domains {
+		if domain == excludedDomain {
+			continue
+		}
+		pinning, err := workload.GetDomainPinning(domain)
+		if err != nil {
+			return nil, err
+		}
+		if workload.IsPinned(pinning.CoreIds(), onlineIds) {
+			coreIds = append(coreIds, pinning.CoreIds()...)
+		}
+	}
+	return coreIds, nil
+}
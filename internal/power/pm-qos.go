@@ -0,0 +1,122 @@
+package power
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"os"
+	"strconv"
+)
+
+type pmQosState struct {
+	origValues    map[int]string
+	cpuDmaLatency *os.File
+}
+
+// maxWakeLatencyUs returns the configured wake latency constraint of a pool, or zero if unconstrained.
+func (o *SleepController) maxWakeLatencyUs(poolName string) int {
+	if poolName == StablePool {
+		return o.conf.PowerProfile.StableMaxWakeLatencyUs
+	}
+	return o.conf.PowerProfile.DynamicMaxWakeLatencyUs
+}
+
+// setupWakeLatency applies the configured per-pool and system-wide wake latency constraints.
+func (o *SleepController) setupWakeLatency() error {
+	pools := map[string][]int{
+		StablePool:  o.sleepState.stableCpuIds,
+		DynamicPool: o.sleepState.dynamicCpuIds,
+	}
+	for poolName, coreIds := range pools {
+		if o.maxWakeLatencyUs(poolName) <= 0 {
+			continue
+		}
+		err := o.setPoolWakeLatency(poolName, coreIds, o.maxWakeLatencyUs(poolName))
+		if err != nil {
+			return err
+		}
+	}
+	if o.conf.PowerProfile.GlobalMaxWakeLatencyUs > 0 {
+		return o.holdCpuDmaLatency(o.conf.PowerProfile.GlobalMaxWakeLatencyUs)
+	}
+	return nil
+}
+
+// followSleepWithWakeLatency lifts the wake latency constraint of dynamic cores while they sleep, such that they
+// can enter deep idle states, and re-applies it upon wake.
+func (o *SleepController) followSleepWithWakeLatency(asleep bool) error {
+	latencyUs := o.maxWakeLatencyUs(DynamicPool)
+	if latencyUs <= 0 {
+		return nil
+	}
+	if asleep {
+		latencyUs = 0
+	}
+	return o.setPoolWakeLatency(DynamicPool, o.sleepState.dynamicCpuIds, latencyUs)
+}
+
+// setPoolWakeLatency constrains the resume latency of pool cores via per-cpu pm qos, such that cpuidle governors
+// only pick idle states that wake up within the latency. A latency of zero removes the constraint.
+func (o *SleepController) setPoolWakeLatency(poolName string, coreIds []int, latencyUs int) error {
+	if o.pmQos.origValues == nil {
+		o.pmQos.origValues = map[int]string{}
+	}
+	value := sysfs.UnconstrainedResumeLatency
+	if latencyUs > 0 {
+		value = strconv.Itoa(latencyUs)
+	}
+	for _, coreId := range coreIds {
+		path := sysfs.CpuFile(coreId, sysfs.ResumeLatencyFile)
+		if _, saved := o.pmQos.origValues[coreId]; !saved {
+			orig, err := sysfs.ReadString(path)
+			if err != nil {
+				return err
+			}
+			o.pmQos.origValues[coreId] = orig
+		}
+		err := sysfs.WriteString(path, value)
+		if err != nil {
+			return fmt.Errorf("failed at setting wake latency of core: %d: %w", coreId, err)
+		}
+	}
+	log.Printf("wake latency of pool: %s constrained to: %s us", poolName, value)
+	return nil
+}
+
+// holdCpuDmaLatency requests a system-wide wake latency via /dev/cpu_dma_latency. The request is held as long as the
+// device stays open.
+func (o *SleepController) holdCpuDmaLatency(latencyUs int) error {
+	file, err := os.OpenFile(sysfs.CpuDmaLatencyDevPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed at opening %s: %w", sysfs.CpuDmaLatencyDevPath, err)
+	}
+	request := make([]byte, 4)
+	binary.NativeEndian.PutUint32(request, uint32(latencyUs))
+	_, err = file.Write(request)
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed at requesting system-wide wake latency: %w", err)
+	}
+	o.pmQos.cpuDmaLatency = file
+	log.Printf("holding system-wide wake latency of %d us", latencyUs)
+	return nil
+}
+
+func (o *SleepController) restorePmQos() error {
+	for coreId, value := range o.pmQos.origValues {
+		err := sysfs.WriteString(sysfs.CpuFile(coreId, sysfs.ResumeLatencyFile), value)
+		if err != nil {
+			return fmt.Errorf("failed at restoring wake latency of core: %d: %w", coreId, err)
+		}
+	}
+	o.pmQos.origValues = nil
+	if o.pmQos.cpuDmaLatency != nil {
+		err := o.pmQos.cpuDmaLatency.Close()
+		if err != nil {
+			return fmt.Errorf("failed at releasing system-wide wake latency: %w", err)
+		}
+		o.pmQos.cpuDmaLatency = nil
+	}
+	return nil
+}
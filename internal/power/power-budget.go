@@ -0,0 +1,233 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+type powerBudgetState struct {
+	stop         func()
+	lastEnergyUj map[string]uint
+	lastSample   time.Time
+	flags        budgetFlags
+	origLimits   map[string]string
+	stats        model.PowerBudgetStats
+}
+
+// budgetFlags tell what the budget did to dynamic cores, which is all it may undo.
+type budgetFlags struct {
+	throttled     bool
+	sleptByBudget bool
+}
+
+// StartPowerBudget periodically measures package power via RAPL, and keeps it under the configured budget by stepping
+// the frequency of dynamic cores down and eventually putting them to sleep. Once power drops below the budget by the
+// hysteresis, dynamic cores are woken and stepped back up to the perf frequency.
+func (o *SleepController) StartPowerBudget() {
+	if o.isEmulate || !o.conf.PowerBudget.Enabled || o.powerBudget.stop != nil {
+		return
+	}
+	interval := time.Duration(o.conf.PowerBudget.IntervalSec) * time.Second
+	if interval <= 0 || o.conf.PowerBudget.PackageWatts <= 0 {
+		log.Println("power budget interval or package watts is not set, power budget is disabled")
+		return
+	}
+	if o.conf.PowerBudget.ProgramRapl {
+		err := o.programRaplLimits()
+		if err != nil {
+			log.Printf("unable to program rapl power limits, enforcing the budget only by sleeping cores: %v", err)
+		}
+	}
+	o.powerBudget.stats.BudgetWatts = o.conf.PowerBudget.PackageWatts
+
+	log.Printf("starting power budget with interval: %s, limiting packages to %d W", interval,
+		o.conf.PowerBudget.PackageWatts)
+	o.powerBudget.stop = startLoop(interval, false, o.enforcePowerBudget)
+}
+
+func (o *SleepController) StopPowerBudget() {
+	o.stopLoop(&o.powerBudget.stop)
+}
+
+// PowerBudgetStats returns the last package power readings along with budget compliance.
+func (o *SleepController) PowerBudgetStats() model.PowerBudgetStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := o.powerBudget.stats
+	stats.PackageWatts = map[string]float64{}
+	for zone, watts := range o.powerBudget.stats.PackageWatts {
+		stats.PackageWatts[zone] = watts
+	}
+	return stats
+}
+
+func (o *SleepController) enforcePowerBudget() {
+	o.mu.Lock()
+	packageWatts, err := o.readPackageWatts()
+	if err != nil {
+		o.mu.Unlock()
+		log.Printf("failed at reading package power: %v", err)
+		return
+	}
+	if packageWatts == nil {
+		// first sample only sets the baseline energy.
+		o.mu.Unlock()
+		return
+	}
+	maxWatts := 0.0
+	for _, watts := range packageWatts {
+		maxWatts = max(maxWatts, watts)
+	}
+	stats := &o.powerBudget.stats
+	stats.PackageWatts = packageWatts
+	stats.LastCheck = time.Now()
+	stats.Samples++
+	if maxWatts > float64(o.conf.PowerBudget.PackageWatts) {
+		stats.SamplesOverBudget++
+	}
+	stats.CompliancePct = 100 * float64(stats.Samples-stats.SamplesOverBudget) / float64(stats.Samples)
+	flags, action := o.nextBudgetAction(maxWatts)
+	o.mu.Unlock()
+
+	if action == nil {
+		return
+	}
+	// the flags only change once the action succeeds, such that a refused action is retried upon the next sample.
+	err = action()
+	if err != nil {
+		log.Printf("failed at enforcing power budget: %v", err)
+		return
+	}
+	o.mu.Lock()
+	o.powerBudget.flags = flags
+	stats.Throttled = flags.throttled
+	stats.SleptByBudget = flags.sleptByBudget
+	o.mu.Unlock()
+}
+
+// nextBudgetAction decides how to move dynamic cores for the given package power, and returns the flags to keep once
+// the action succeeds, or a nil action if nothing changes. Only cores throttled or slept by the budget are raised
+// again, such that manual sleep requests are left intact.
+func (o *SleepController) nextBudgetAction(maxWatts float64) (budgetFlags, func() error) {
+	budget := o.conf.PowerBudget
+	sleepFMhz, perfFMhz := uint(o.conf.PowerProfile.SleepFrq), uint(o.conf.PowerProfile.PerfFrq)
+	stepFMhz := uint(max(budget.StepFrq, 1))
+	dynProfile := o.profiles[DynamicPool]
+	sleepProfile := o.newPoolProfile(o.conf.PowerProfile.SleepIdleState, sleepFMhz)
+	isAwake := len(o.sleepState.offlineCpuIds) == 0 && dynProfile != sleepProfile
+	flags := o.powerBudget.flags
+
+	switch {
+	case maxWatts > float64(budget.PackageWatts) && isAwake:
+		if o.features.freqScaling && dynProfile.fMhz > sleepFMhz {
+			fMhz := max(sleepFMhz, dynProfile.fMhz-min(stepFMhz, dynProfile.fMhz))
+			flags.throttled = true
+			log.Printf("package power: %.1f W exceeds the budget, lowering dynamic pool to %d MHz", maxWatts, fMhz)
+			return flags, func() error { return o.OpFrequency(fMhz) }
+		}
+		flags.sleptByBudget = true
+		log.Printf("package power: %.1f W exceeds the budget, putting dynamic pool to sleep", maxWatts)
+		return flags, o.SleepUnlessHeld
+	case maxWatts >= float64(budget.PackageWatts-budget.HysteresisWatts):
+		return flags, nil
+	case flags.sleptByBudget:
+		flags.sleptByBudget = false
+		flags.throttled = o.features.freqScaling
+		log.Printf("package power: %.1f W is within the budget, waking dynamic pool", maxWatts)
+		return flags, func() error {
+			err := o.Wake()
+			if err != nil || !o.features.freqScaling {
+				return err
+			}
+			return o.OpFrequency(sleepFMhz)
+		}
+	case flags.throttled && isAwake && o.features.freqScaling:
+		fMhz := min(perfFMhz, dynProfile.fMhz+stepFMhz)
+		flags.throttled = fMhz < perfFMhz
+		log.Printf("package power: %.1f W is within the budget, raising dynamic pool to %d MHz", maxWatts, fMhz)
+		return flags, func() error { return o.OpFrequency(fMhz) }
+	}
+	return flags, nil
+}
+
+// readPackageWatts returns the average power of each package since the previous call, or nil upon the first call.
+func (o *SleepController) readPackageWatts() (map[string]float64, error) {
+	zones, err := sysfs.RaplPackageZones()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	energyUj := map[string]uint{}
+	for zone, dir := range zones {
+		energyUj[zone], err = sysfs.ReadUint(filepath.Join(dir, sysfs.EnergyFile))
+		if err != nil {
+			return nil, err
+		}
+	}
+	lastEnergyUj, elapsed := o.powerBudget.lastEnergyUj, now.Sub(o.powerBudget.lastSample)
+	o.powerBudget.lastEnergyUj, o.powerBudget.lastSample = energyUj, now
+	if lastEnergyUj == nil || elapsed <= 0 {
+		return nil, nil
+	}
+
+	packageWatts := map[string]float64{}
+	for zone, energy := range energyUj {
+		last, found := lastEnergyUj[zone]
+		if !found {
+			continue
+		}
+		consumedUj := energy - last
+		if energy < last {
+			// the energy counter wrapped around.
+			maxRangeUj, err := sysfs.ReadUint(filepath.Join(zones[zone], sysfs.MaxEnergyRangeFile))
+			if err != nil {
+				return nil, err
+			}
+			consumedUj = maxRangeUj - last + energy
+		}
+		packageWatts[zone] = float64(consumedUj) / elapsed.Seconds() / 1e6
+	}
+	return packageWatts, nil
+}
+
+// programRaplLimits sets the long-term RAPL power limit of every package to the budget, remembering the original
+// limits to restore upon clean up.
+func (o *SleepController) programRaplLimits() error {
+	zones, err := sysfs.RaplPackageZones()
+	if err != nil {
+		return err
+	}
+	o.powerBudget.origLimits = map[string]string{}
+	limitUw := strconv.Itoa(o.conf.PowerBudget.PackageWatts * 1000000)
+	for zone, dir := range zones {
+		path := filepath.Join(dir, sysfs.RaplPowerLimitFile)
+		orig, err := sysfs.ReadString(path)
+		if err != nil {
+			return err
+		}
+		err = sysfs.WriteString(path, limitUw)
+		if err != nil {
+			return fmt.Errorf("failed at limiting power of %s: %w", zone, err)
+		}
+		o.powerBudget.origLimits[path] = orig
+		log.Printf("rapl power limit of %s set to %d W", zone, o.conf.PowerBudget.PackageWatts)
+	}
+	return nil
+}
+
+func (o *SleepController) restoreRaplLimits() error {
+	for path, value := range o.powerBudget.origLimits {
+		err := sysfs.WriteString(path, value)
+		if err != nil {
+			return fmt.Errorf("failed at restoring rapl power limit: %w", err)
+		}
+	}
+	o.powerBudget.origLimits = nil
+	return nil
+}
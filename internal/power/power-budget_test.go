@@ -0,0 +1,61 @@
+package power
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"testing"
+)
+
+func TestNextBudgetAction(t *testing.T) {
+	full := featureMatrix{cStates: true, freqScaling: true}
+	cases := []struct {
+		name        string
+		features    featureMatrix
+		profile     poolProfile
+		offlineIds  []int
+		flags       budgetFlags
+		maxWatts    float64
+		wantFlags   budgetFlags
+		wantsAction bool
+	}{
+		{name: "over budget throttles", features: full, profile: poolProfile{"POLL", 2400}, maxWatts: 120,
+			wantFlags: budgetFlags{throttled: true}, wantsAction: true},
+		{name: "over budget at sleep frequency sleeps", features: full, profile: poolProfile{"POLL", 800},
+			flags: budgetFlags{throttled: true}, maxWatts: 120,
+			wantFlags: budgetFlags{throttled: true, sleptByBudget: true}, wantsAction: true},
+		{name: "over budget without frequency scaling sleeps", features: featureMatrix{cStates: true},
+			profile: poolProfile{idleState: "POLL"}, maxWatts: 120, wantFlags: budgetFlags{sleptByBudget: true},
+			wantsAction: true},
+		{name: "over budget while asleep", features: full, profile: poolProfile{"C6", 800}, maxWatts: 120},
+		{name: "over budget while offline", features: full, profile: poolProfile{"POLL", 2400}, offlineIds: []int{3},
+			maxWatts: 120},
+		{name: "within hysteresis", features: full, profile: poolProfile{"POLL", 2000},
+			flags: budgetFlags{throttled: true}, maxWatts: 95, wantFlags: budgetFlags{throttled: true}},
+		{name: "under budget wakes slept cores", features: full, profile: poolProfile{"C6", 800},
+			flags: budgetFlags{throttled: true, sleptByBudget: true}, maxWatts: 80,
+			wantFlags: budgetFlags{throttled: true}, wantsAction: true},
+		{name: "under budget raises throttled cores", features: full, profile: poolProfile{"POLL", 2000},
+			flags: budgetFlags{throttled: true}, maxWatts: 80, wantFlags: budgetFlags{throttled: true},
+			wantsAction: true},
+		{name: "under budget restores perf frequency", features: full, profile: poolProfile{"POLL", 2300},
+			flags: budgetFlags{throttled: true}, maxWatts: 80, wantsAction: true},
+		{name: "under budget leaves manual sleep", features: full, profile: poolProfile{"C6", 800}, maxWatts: 80},
+	}
+	for _, c := range cases {
+		o := &SleepController{
+			conf: model.ConfYaml{
+				PowerProfile: model.PowerProfile{SleepIdleState: "C6", SleepFrq: 800, PerfIdleState: "POLL",
+					PerfFrq: 2400},
+				PowerBudget: model.PowerBudget{PackageWatts: 100, HysteresisWatts: 10, StepFrq: 200},
+			},
+			features:    c.features,
+			profiles:    map[string]poolProfile{DynamicPool: c.profile},
+			sleepState:  CoreSleeps{offlineCpuIds: c.offlineIds},
+			powerBudget: powerBudgetState{flags: c.flags},
+		}
+		flags, action := o.nextBudgetAction(c.maxWatts)
+		if flags != c.wantFlags || (action != nil) != c.wantsAction {
+			t.Errorf("%s: nextBudgetAction(%.0f) = %+v, action: %t, want %+v, action: %t", c.name, c.maxWatts,
+				flags, action != nil, c.wantFlags, c.wantsAction)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package power
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"log"
+	"time"
+)
+
+const maxRecentDriftEvents = 50
+
+type reconcilerState struct {
+	stop  func()
+	stats model.DriftStats
+}
+
+// StartReconciler periodically compares the expected pool profiles against sysfs, and re-applies them upon drift
+// if auto-correction is enabled. Other power managers (ex: tuned, cpupower) can change core settings underneath
+// the controller.
+func (o *SleepController) StartReconciler() {
+	if o.isEmulate || !o.conf.Reconciliation.Enabled || o.reconciler.stop != nil {
+		return
+	}
+	interval := time.Duration(o.conf.Reconciliation.IntervalSec) * time.Second
+	if interval <= 0 {
+		log.Println("reconciliation interval is not set, drift detection is disabled")
+		return
+	}
+	o.reconciler.stats.AutoCorrect = o.conf.Reconciliation.AutoCorrect
+
+	log.Printf("starting drift reconciler with interval: %s, auto-correct: %t", interval,
+		o.conf.Reconciliation.AutoCorrect)
+	o.reconciler.stop = startLoop(interval, false, o.reconcile)
+}
+
+func (o *SleepController) StopReconciler() {
+	o.stopLoop(&o.reconciler.stop)
+}
+
+// DriftStats returns reconciliation metrics along with recent drift events.
+func (o *SleepController) DriftStats() model.DriftStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := o.reconciler.stats
+	stats.RecentEvents = append([]model.DriftEvent{}, o.reconciler.stats.RecentEvents...)
+	return stats
+}
+
+func (o *SleepController) reconcile() {
+	if !o.opMu.TryLock() {
+		// pools are being changed, and are checked upon the next interval instead of being mistaken for drifted.
+		return
+	}
+	defer o.opMu.Unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.reconciler.stats.Checks++
+	o.reconciler.stats.LastCheck = time.Now()
+	pools := map[string][]int{
+		StablePool:   o.sleepState.stableCpuIds,
+		DynamicPool:  o.sleepState.dynamicCpuIds,
+		ReservedPool: o.sleepState.reservedCpuIds,
+	}
+	for poolName, coreIds := range pools {
+		if poolName == DynamicPool && len(o.sleepState.offlineCpuIds) > 0 {
+			o.reconcileOfflineCores()
+			continue
+		}
+		profile := o.profiles[poolName]
+		diffs, err := o.verifyCores(poolName, coreIds, profile)
+		if err != nil {
+			log.Printf("failed at checking pool: %s for drift: %v", poolName, err)
+			continue
+		}
+		if len(diffs) == 0 {
+			continue
+		}
+
+		event := model.DriftEvent{
+			Time:  time.Now(),
+			Pool:  poolName,
+			Diffs: diffs,
+		}
+		o.reconciler.stats.DriftsDetected++
+		log.Printf("drift detected: %v", &VerificationError{PoolName: poolName, Diffs: diffs})
+		if o.conf.Reconciliation.AutoCorrect {
+			err = o.applyPoolProfile(poolName, profile)
+			if err != nil {
+				log.Printf("failed at correcting drift of pool: %s: %v", poolName, err)
+			} else {
+				event.Corrected = true
+				o.reconciler.stats.Corrections++
+				log.Printf("pool: %s restored to idle state: %s and frequency: %d", poolName, profile.idleState,
+					profile.fMhz)
+			}
+		}
+		o.recordDriftEvent(event)
+	}
+}
+
+// reconcileOfflineCores detects offlined dynamic cores brought back online by someone else.
+func (o *SleepController) reconcileOfflineCores() {
+	diffs := onlineDiffs(o.sleepState.offlineCpuIds)
+	if len(diffs) == 0 {
+		return
+	}
+	event := model.DriftEvent{
+		Time:  time.Now(),
+		Pool:  DynamicPool,
+		Diffs: diffs,
+	}
+	o.reconciler.stats.DriftsDetected++
+	log.Printf("drift detected: %v", &VerificationError{PoolName: DynamicPool, Diffs: diffs})
+	if o.conf.Reconciliation.AutoCorrect {
+		var coreIds []int
+		for _, diff := range diffs {
+			coreIds = append(coreIds, diff.CoreId)
+		}
+		err := o.applyPoolProfile(DynamicPool, o.profiles[DynamicPool])
+		if err == nil {
+			err = setCoresOnline(DynamicPool, coreIds, false)
+		}
+		if err != nil {
+			log.Printf("failed at taking cores: %v offline again: %v", coreIds, err)
+		} else {
+			event.Corrected = true
+			o.reconciler.stats.Corrections++
+		}
+	}
+	o.recordDriftEvent(event)
+}
+
+func (o *SleepController) recordDriftEvent(event model.DriftEvent) {
+	events := append(o.reconciler.stats.RecentEvents, event)
+	if len(events) > maxRecentDriftEvents {
+		events = events[len(events)-maxRecentDriftEvents:]
+	}
+	o.reconciler.stats.RecentEvents = events
+}
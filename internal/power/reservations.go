@@ -0,0 +1,270 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/state"
+	"log"
+	"slices"
+	"time"
+)
+
+const reservationsStateName = "reservations"
+
+var (
+	ErrReservationExists   = errors.New("owner already holds a reservation")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrInsufficientCores   = errors.New("not enough unreserved dynamic cores")
+)
+
+type reservationState struct {
+	claims        *state.Records[model.Reservation]
+	isPoolCreated bool
+}
+
+// StartReservations restores persisted reservations, such that workloads keep their cores across restarts.
+func (o *SleepController) StartReservations() {
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.reservations.claims = state.NewRecords(state.NewStore(o.conf.Host.StateDir), reservationsStateName,
+		func(claim model.Reservation) string { return claim.Owner },
+		func(claim model.Reservation) time.Time { return claim.Created })
+	persisted, err := o.reservations.claims.Load()
+	if err != nil {
+		log.Printf("unable to restore reservations: %v", err)
+	}
+	for _, claim := range o.reservations.claims.List() {
+		err = o.reserveCores(claim.Owner, claim.CoreIds)
+		if err != nil {
+			log.Printf("unable to restore reservation of owner: %s: %v", claim.Owner, err)
+			o.reservations.claims.Delete(claim.Owner)
+		}
+	}
+	if persisted > 0 {
+		log.Printf("restored %d of %d reservations", o.reservations.claims.Len(), persisted)
+		err = o.reservations.claims.Save()
+		if err != nil {
+			log.Printf("unable to persist reservations: %v", err)
+		}
+	}
+}
+
+// Reserve claims the given number of dynamic cores for an owner (ex: a vm uuid or a container id). Claimed cores are
+// woken and kept awake until the reservation is released.
+func (o *SleepController) Reserve(owner string, coreCount int) (*model.Reservation, error) {
+	if owner == "" || coreCount <= 0 {
+		return nil, fmt.Errorf("reservation needs an owner and a positive core count")
+	}
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, exists := o.reservations.claims.Get(owner); exists {
+		return nil, ErrReservationExists
+	}
+	if coreCount > len(o.sleepState.dynamicCpuIds) {
+		return nil, ErrInsufficientCores
+	}
+	// cores are claimed from the end of the pool, such that the dynamic pool keeps its lowest core ids.
+	coreIds := slices.Clone(o.sleepState.dynamicCpuIds[len(o.sleepState.dynamicCpuIds)-coreCount:])
+	err := o.reserveCores(owner, coreIds)
+	if err != nil {
+		return nil, err
+	}
+	claim := model.Reservation{Owner: owner, CoreIds: coreIds, Created: time.Now()}
+	o.reservations.claims.Put(claim)
+	err = o.reservations.claims.Save()
+	if err != nil {
+		// a reservation which is not persisted would be lost upon restart, and is therefore returned right away.
+		o.reservations.claims.Delete(owner)
+		returnErr := o.returnCores(coreIds)
+		if returnErr != nil {
+			log.Printf("unable to return cores: %v of the unsaved reservation of owner: %s: %v", coreIds, owner,
+				returnErr)
+		}
+		return nil, fmt.Errorf("failed at saving the reservation of owner: %s: %w", owner, err)
+	}
+	log.Printf("cores: %v reserved for owner: %s", coreIds, owner)
+	return &claim, nil
+}
+
+// Release returns the cores of an owner to the dynamic pool, which puts them to sleep if the pool sleeps.
+func (o *SleepController) Release(owner string) error {
+	o.opMu.Lock()
+	defer o.opMu.Unlock()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	claim, exists := o.reservations.claims.Get(owner)
+	if !exists {
+		return ErrReservationNotFound
+	}
+	err := o.returnCores(claim.CoreIds)
+	if err != nil {
+		return err
+	}
+	o.reservations.claims.Delete(owner)
+	log.Printf("cores: %v released by owner: %s", claim.CoreIds, owner)
+	return o.reservations.claims.Save()
+}
+
+// Reservations lists the reservations in the order they were claimed.
+func (o *SleepController) Reservations() []model.Reservation {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.reservations.claims.List()
+}
+
+// reserveCores moves the given dynamic cores into the reserved pool, and applies the perf profile to them. If any step
+// fails, the cores are moved back to the dynamic pool in their previous state.
+func (o *SleepController) reserveCores(owner string, coreIds []int) (err error) {
+	// reserved cores are woken, which would undo the load shedding of a demand response event.
+	err = o.checkDemandResponse("reserving dynamic cores")
+	if err != nil {
+		return err
+	}
+	for _, coreId := range coreIds {
+		if !slices.Contains(o.sleepState.dynamicCpuIds, coreId) {
+			return fmt.Errorf("core: %d is not an unreserved dynamic core", coreId)
+		}
+	}
+
+	// state slices are replaced rather than modified, such that the previous ones are intact for a rollback.
+	prevState := o.sleepState
+	var offlineIds []int
+	isMoved := false
+	defer func() {
+		if err != nil {
+			o.rollbackReservation(coreIds, offlineIds, isMoved, prevState)
+		}
+	}()
+	if !o.isEmulate {
+		for _, coreId := range coreIds {
+			if slices.Contains(o.sleepState.offlineCpuIds, coreId) {
+				offlineIds = append(offlineIds, coreId)
+			}
+		}
+		if len(offlineIds) > 0 {
+			err = setCoresOnline(DynamicPool, offlineIds, true)
+			if err != nil {
+				return err
+			}
+			o.sleepState.offlineCpuIds = withoutIds(o.sleepState.offlineCpuIds, offlineIds)
+		}
+		if !o.reservations.isPoolCreated {
+			err = o.backend.AddPool(ReservedPool, nil)
+			if err != nil {
+				return fmt.Errorf("failed at creating the reserved pool: %w", err)
+			}
+			o.reservations.isPoolCreated = true
+		}
+		err = o.backend.MoveCores(ReservedPool, toUintIds(coreIds))
+		if err != nil {
+			return err
+		}
+		isMoved = true
+	}
+	o.sleepState.dynamicCpuIds = withoutIds(o.sleepState.dynamicCpuIds, coreIds)
+	o.sleepState.reservedCpuIds = append(slices.Clone(o.sleepState.reservedCpuIds), coreIds...)
+	if o.isEmulate {
+		return nil
+	}
+
+	profile := o.newPoolProfile(o.conf.PowerProfile.PerfIdleState, uint(o.conf.PowerProfile.PerfFrq))
+	err = o.applyAndVerify(ReservedPool, o.sleepState.reservedCpuIds, profile, func() error {
+		return o.applyPoolProfile(ReservedPool, profile)
+	})
+	if err != nil {
+		return fmt.Errorf("failed at waking cores reserved for owner: %s: %w", owner, err)
+	}
+	o.profiles[ReservedPool] = profile
+	if latencyUs := o.maxWakeLatencyUs(DynamicPool); latencyUs > 0 {
+		return o.setPoolWakeLatency(ReservedPool, coreIds, latencyUs)
+	}
+	return nil
+}
+
+// rollbackReservation undoes a partially applied reservation, bringing the cores back to the dynamic pool and to the
+// sleep state they had. Failures are logged, since the reservation error is the one reported.
+func (o *SleepController) rollbackReservation(coreIds []int, offlineIds []int, isMoved bool, prevState CoreSleeps) {
+	o.sleepState = prevState
+	if o.isEmulate {
+		return
+	}
+	if isMoved {
+		err := o.backend.MoveCores(DynamicPool, toUintIds(coreIds))
+		if err != nil {
+			log.Printf("unable to move cores: %v back to the dynamic pool: %v", coreIds, err)
+			return
+		}
+		profile := o.profiles[DynamicPool]
+		err = o.applyPoolProfile(DynamicPool, profile)
+		if err != nil {
+			log.Printf("unable to re-apply the dynamic pool profile to cores: %v: %v", coreIds, err)
+		}
+	}
+	if len(offlineIds) > 0 {
+		err := setCoresOnline(DynamicPool, offlineIds, false)
+		if err != nil {
+			log.Printf("unable to take cores: %v back offline: %v", offlineIds, err)
+			o.sleepState.offlineCpuIds = withoutIds(o.sleepState.offlineCpuIds, offlineIds)
+		}
+	}
+}
+
+// returnCores moves reserved cores back into the dynamic pool, and brings them to the state of the pool.
+func (o *SleepController) returnCores(coreIds []int) error {
+	if !o.isEmulate {
+		err := o.backend.MoveCores(DynamicPool, toUintIds(coreIds))
+		if err != nil {
+			return err
+		}
+	}
+	o.sleepState.reservedCpuIds = withoutIds(o.sleepState.reservedCpuIds, coreIds)
+	dynamicCpuIds := append(slices.Clone(o.sleepState.dynamicCpuIds), coreIds...)
+	slices.Sort(dynamicCpuIds)
+	o.sleepState.dynamicCpuIds = dynamicCpuIds
+	if o.isEmulate {
+		return nil
+	}
+
+	if len(o.sleepState.offlineCpuIds) > 0 {
+		// cores lose their settings while offline, and the dynamic pool profile is re-applied upon wake.
+		err := setCoresOnline(DynamicPool, coreIds, false)
+		if err != nil {
+			return err
+		}
+		o.sleepState.offlineCpuIds = append(slices.Clone(o.sleepState.offlineCpuIds), coreIds...)
+		return nil
+	}
+	profile := o.profiles[DynamicPool]
+	err := o.applyAndVerify(DynamicPool, coreIds, profile, func() error {
+		return o.applyPoolProfile(DynamicPool, profile)
+	})
+	if err != nil {
+		return fmt.Errorf("failed at returning cores: %v to the dynamic pool: %w", coreIds, err)
+	}
+	if latencyUs := o.maxWakeLatencyUs(DynamicPool); latencyUs > 0 && o.sleepState.isDynamicCoresAsleep {
+		return o.setPoolWakeLatency(DynamicPool, coreIds, 0)
+	}
+	return nil
+}
+
+func toUintIds(ids []int) []uint {
+	var uintIds []uint
+	for _, id := range ids {
+		uintIds = append(uintIds, uint(id))
+	}
+	return uintIds
+}
+
+// withoutIds returns a new slice of the ids, leaving out the removed ones.
+func withoutIds(ids []int, removed []int) []int {
+	return slices.DeleteFunc(slices.Clone(ids), func(id int) bool { return slices.Contains(removed, id) })
+}
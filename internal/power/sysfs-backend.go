@@ -0,0 +1,247 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"slices"
+	"strings"
+)
+
+// sysfsBackend manages cores through the generic cpufreq and cpuidle sysfs interfaces of linux. It supports any
+// idle driver (ex: acpi_idle) and cpufreq driver (ex: amd-pstate), hence non-intel processors.
+type sysfsBackend struct {
+	features    featureMatrix
+	cpuIds      []uint
+	idleStates  []string
+	pools       map[string][]int
+	origConfigs map[int]coreConfig
+}
+
+// coreConfig is the power configuration of a core prior to pooling, restored upon clean up.
+type coreConfig struct {
+	governor      string
+	minFKHz       uint
+	maxFKHz       uint
+	idleDisabled  []string
+	hasGovernor   bool
+	hasFrequency  bool
+	hasIdleStates bool
+}
+
+func newSysfsBackend() (*sysfsBackend, error) {
+	ids, err := sysfs.CpuIds()
+	if err != nil {
+		return nil, err
+	}
+	var cpuIds []uint
+	for _, id := range ids {
+		cpuIds = append(cpuIds, uint(id))
+	}
+
+	idleDriver, idleDriverErr := sysfs.ReadString(sysfs.CpuPath + "/" + sysfs.IdleDriverFile)
+	idleStates, _ := sysfs.IdleStateNames(0)
+	cStates := idleDriverErr == nil && idleDriver != "none" && len(idleStates) > 0
+	if !cStates {
+		log.Printf("c-states are not supported, idle states will not be managed. idle driver: %s", idleDriver)
+	}
+	freqDriver, freqDriverErr := sysfs.ReadString(sysfs.CpuFile(0, sysfs.ScalingDriverFile))
+	if freqDriverErr != nil {
+		log.Printf("frequency scaling is not supported, frequencies will not be managed: %v", freqDriverErr)
+	}
+	log.Printf("managing cores via sysfs. idle driver: %s, cpufreq driver: %s", idleDriver, freqDriver)
+
+	return &sysfsBackend{
+		features:    featureMatrix{cStates: cStates, freqScaling: freqDriverErr == nil},
+		cpuIds:      cpuIds,
+		idleStates:  idleStates,
+		pools:       map[string][]int{},
+		origConfigs: map[int]coreConfig{},
+	}, nil
+}
+
+func (b *sysfsBackend) Name() string {
+	return SysfsBackend
+}
+
+func (b *sysfsBackend) Features() featureMatrix {
+	return b.features
+}
+
+func (b *sysfsBackend) CpuIds() []uint {
+	return b.cpuIds
+}
+
+func (b *sysfsBackend) AddPool(poolName string, coreIds []uint) error {
+	if _, exists := b.pools[poolName]; exists {
+		return fmt.Errorf("pool: %s already exists", poolName)
+	}
+	var cpuIds []int
+	for _, id := range coreIds {
+		cpuId := int(id)
+		for name, pool := range b.pools {
+			if slices.Contains(pool, cpuId) {
+				return fmt.Errorf("core: %d already belongs to pool: %s", cpuId, name)
+			}
+		}
+		config, err := b.readCoreConfig(cpuId)
+		if err != nil {
+			return fmt.Errorf("failed at reading configurations of core: %d: %w", cpuId, err)
+		}
+		b.origConfigs[cpuId] = config
+		cpuIds = append(cpuIds, cpuId)
+	}
+	b.pools[poolName] = cpuIds
+	return nil
+}
+
+func (b *sysfsBackend) MoveCores(poolName string, coreIds []uint) error {
+	if _, exists := b.pools[poolName]; !exists {
+		return fmt.Errorf("pool: %s does not exist", poolName)
+	}
+	for _, id := range coreIds {
+		cpuId := int(id)
+		if _, pooled := b.origConfigs[cpuId]; !pooled {
+			return fmt.Errorf("core: %d does not belong to any pool", cpuId)
+		}
+		for name, pool := range b.pools {
+			b.pools[name] = slices.DeleteFunc(pool, func(poolCpuId int) bool { return poolCpuId == cpuId })
+		}
+		b.pools[poolName] = append(b.pools[poolName], cpuId)
+	}
+	return nil
+}
+
+func (b *sysfsBackend) AvailableIdleStates() []string {
+	if !b.features.cStates {
+		return []string{}
+	}
+	return b.idleStates
+}
+
+func (b *sysfsBackend) SetPoolIdleState(poolName string, idleState string) error {
+	cpuIds, exists := b.pools[poolName]
+	if !exists {
+		return fmt.Errorf("pool: %s does not exist", poolName)
+	}
+	if !slices.Contains(b.idleStates, idleState) {
+		return fmt.Errorf("idle state: %s is not available. available idle states: %v", idleState, b.idleStates)
+	}
+	log.Printf("setting pool: %s idle state as %s ...", poolName, idleState)
+	for _, cpuId := range cpuIds {
+		for i, state := range b.idleStates {
+			disable := "1"
+			if state == idleState {
+				disable = "0"
+			}
+			err := sysfs.WriteString(sysfs.IdleStateFile(cpuId, i, "disable"), disable)
+			if err != nil {
+				return fmt.Errorf("failed at setting %s pool to %s: %w", poolName, idleState, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *sysfsBackend) SetPoolFrequency(poolName string, minFMhz uint, maxFMhz uint) error {
+	cpuIds, exists := b.pools[poolName]
+	if !exists {
+		return fmt.Errorf("pool: %s does not exist", poolName)
+	}
+	if minFMhz > maxFMhz {
+		return fmt.Errorf("max frequency can't be lower than min")
+	}
+	for _, cpuId := range cpuIds {
+		governors, err := sysfs.ReadString(sysfs.CpuFile(cpuId, sysfs.ScalingAvailableGovernorsFile))
+		if err == nil && slices.Contains(strings.Fields(governors), "performance") {
+			err = sysfs.WriteString(sysfs.CpuFile(cpuId, sysfs.ScalingGovernorFile), "performance")
+			if err != nil {
+				return fmt.Errorf("failed at setting governor of core: %d: %w", cpuId, err)
+			}
+		}
+		err = writeScalingLimits(cpuId, minFMhz*1000, maxFMhz*1000)
+		if err != nil {
+			return fmt.Errorf("failed at setting %s pool frequency: %w", poolName, err)
+		}
+	}
+	return nil
+}
+
+// Clean restores every core to its original settings, carrying on past the cores which fail. Pools with such cores
+// are kept, such that a later call retries them.
+func (b *sysfsBackend) Clean() error {
+	var errs []error
+	for poolName, cpuIds := range b.pools {
+		isRestored := true
+		for _, cpuId := range cpuIds {
+			err := b.restoreCoreConfig(cpuId, b.origConfigs[cpuId])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed at restoring core: %d of pool: %s: %w", cpuId, poolName, err))
+				isRestored = false
+			}
+		}
+		if isRestored {
+			delete(b.pools, poolName)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (b *sysfsBackend) readCoreConfig(cpuId int) (coreConfig, error) {
+	config := coreConfig{}
+	var err error
+	if b.features.freqScaling {
+		config.governor, err = sysfs.ReadString(sysfs.CpuFile(cpuId, sysfs.ScalingGovernorFile))
+		config.hasGovernor = err == nil
+		config.minFKHz, err = sysfs.ReadUint(sysfs.CpuFile(cpuId, sysfs.ScalingMinFreqFile))
+		if err != nil {
+			return config, err
+		}
+		config.maxFKHz, err = sysfs.ReadUint(sysfs.CpuFile(cpuId, sysfs.ScalingMaxFreqFile))
+		if err != nil {
+			return config, err
+		}
+		config.hasFrequency = true
+	}
+	if b.features.cStates {
+		for i := range b.idleStates {
+			disabled, err := sysfs.ReadString(sysfs.IdleStateFile(cpuId, i, "disable"))
+			if err != nil {
+				return config, err
+			}
+			config.idleDisabled = append(config.idleDisabled, disabled)
+		}
+		config.hasIdleStates = true
+	}
+	return config, nil
+}
+
+func (b *sysfsBackend) restoreCoreConfig(cpuId int, config coreConfig) error {
+	if config.hasGovernor {
+		err := sysfs.WriteString(sysfs.CpuFile(cpuId, sysfs.ScalingGovernorFile), config.governor)
+		if err != nil {
+			return err
+		}
+	}
+	if config.hasFrequency {
+		err := writeScalingLimits(cpuId, config.minFKHz, config.maxFKHz)
+		if err != nil {
+			return err
+		}
+	}
+	if config.hasIdleStates {
+		for i, disabled := range config.idleDisabled {
+			err := sysfs.WriteString(sysfs.IdleStateFile(cpuId, i, "disable"), disabled)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeScalingLimits(cpuId int, minFKHz uint, maxFKHz uint) error {
+	return sysfs.WriteLimits(sysfs.CpuFile(cpuId, sysfs.ScalingMinFreqFile), sysfs.CpuFile(cpuId,
+		sysfs.ScalingMaxFreqFile), minFKHz, maxFKHz)
+}
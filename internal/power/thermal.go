@@ -0,0 +1,167 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"slices"
+	"time"
+)
+
+const (
+	maxRecentThermalEvents = 50
+
+	thermalCapApplied = "cap-applied"
+	thermalCapLifted  = "cap-lifted"
+	thermalWakeRefuse = "wake-refused"
+)
+
+type thermalState struct {
+	stop    func()
+	capFMhz uint
+	stats   model.ThermalStats
+}
+
+// ThermalError is returned when a request is refused because cores run too hot.
+type ThermalError struct {
+	Sensor string
+	TempC  float64
+	LimitC int
+}
+
+func (e *ThermalError) Error() string {
+	return fmt.Sprintf("sensor: %s reads %.1f°C, exceeding the limit of %d°C", e.Sensor, e.TempC, e.LimitC)
+}
+
+// StartThermalGuard periodically reads the temperature of the host, and caps pool frequencies while it exceeds the
+// configured threshold. The cap is lifted once the temperature drops below the threshold by the hysteresis.
+func (o *SleepController) StartThermalGuard() {
+	if o.isEmulate || !o.conf.Thermal.Enabled || o.thermal.stop != nil {
+		return
+	}
+	interval := time.Duration(o.conf.Thermal.IntervalSec) * time.Second
+	if interval <= 0 {
+		log.Println("thermal check interval is not set, thermal guard is disabled")
+		return
+	}
+
+	log.Printf("starting thermal guard with interval: %s, capping at %d MHz above %d°C", interval,
+		o.conf.Thermal.CapFrq, o.conf.Thermal.CapTempC)
+	o.thermal.stop = startLoop(interval, false, o.checkThermal)
+}
+
+func (o *SleepController) StopThermalGuard() {
+	o.stopLoop(&o.thermal.stop)
+}
+
+// ThermalStats returns the last temperature reading along with recent thermal events.
+func (o *SleepController) ThermalStats() model.ThermalStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := o.thermal.stats
+	stats.RecentEvents = append([]model.ThermalEvent{}, o.thermal.stats.RecentEvents...)
+	return stats
+}
+
+// hottestSensor returns the sensor with the highest temperature among the configured ones, or among all sensors if
+// none are configured.
+func (o *SleepController) hottestSensor() (string, float64, error) {
+	temps, err := sysfs.Temperatures()
+	if err != nil {
+		return "", 0, err
+	}
+	hottest, hottestTempC := "", 0.0
+	for sensor, tempC := range temps {
+		if len(o.conf.Thermal.Sensors) > 0 && !slices.Contains(o.conf.Thermal.Sensors, sensor) {
+			continue
+		}
+		if hottest == "" || tempC > hottestTempC {
+			hottest, hottestTempC = sensor, tempC
+		}
+	}
+	if hottest == "" {
+		return "", 0, fmt.Errorf("none of the sensors: %v are exposed by the platform", o.conf.Thermal.Sensors)
+	}
+	return hottest, hottestTempC, nil
+}
+
+func (o *SleepController) checkThermal() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	sensor, tempC, err := o.hottestSensor()
+	if err != nil {
+		log.Printf("failed at reading temperature: %v", err)
+		return
+	}
+	o.thermal.stats.Sensor = sensor
+	o.thermal.stats.TempC = tempC
+	o.thermal.stats.LastCheck = time.Now()
+
+	capTempC := float64(o.conf.Thermal.CapTempC)
+	switch {
+	case o.conf.Thermal.CapTempC <= 0 || o.conf.Thermal.CapFrq <= 0:
+		return
+	case o.thermal.capFMhz == 0 && tempC >= capTempC:
+		o.thermal.capFMhz = uint(o.conf.Thermal.CapFrq)
+		o.recordThermalEvent(thermalCapApplied, sensor, tempC)
+		log.Printf("sensor: %s reads %.1f°C, capping pools at %d MHz", sensor, tempC, o.thermal.capFMhz)
+	case o.thermal.capFMhz > 0 && tempC < capTempC-float64(o.conf.Thermal.HysteresisC):
+		o.thermal.capFMhz = 0
+		o.recordThermalEvent(thermalCapLifted, sensor, tempC)
+		log.Printf("sensor: %s reads %.1f°C, lifting the thermal cap", sensor, tempC)
+	default:
+		return
+	}
+	o.thermal.stats.Capped = o.thermal.capFMhz > 0
+	o.thermal.stats.CapFMhz = int(o.thermal.capFMhz)
+	o.reapplyFrequencies()
+}
+
+// reapplyFrequencies re-applies the frequency of every online pool, such that a changed cap takes effect.
+func (o *SleepController) reapplyFrequencies() {
+	for poolName, profile := range o.profiles {
+		if profile.fMhz == 0 || (poolName == DynamicPool && len(o.sleepState.offlineCpuIds) > 0) {
+			continue
+		}
+		err := o.setPerf(poolName, profile.fMhz)
+		if err != nil {
+			log.Printf("failed at re-applying frequency of pool: %s: %v", poolName, err)
+		}
+	}
+}
+
+// checkWakeTemperature refuses to wake dynamic cores while the host is hotter than the configured limit.
+func (o *SleepController) checkWakeTemperature() error {
+	if o.isEmulate || !o.conf.Thermal.Enabled || o.conf.Thermal.RefuseWakeTempC <= 0 {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	sensor, tempC, err := o.hottestSensor()
+	if err != nil {
+		log.Printf("unable to check temperature before waking: %v", err)
+		return nil
+	}
+	if tempC < float64(o.conf.Thermal.RefuseWakeTempC) {
+		return nil
+	}
+	o.recordThermalEvent(thermalWakeRefuse, sensor, tempC)
+	return &ThermalError{Sensor: sensor, TempC: tempC, LimitC: o.conf.Thermal.RefuseWakeTempC}
+}
+
+func (o *SleepController) recordThermalEvent(action string, sensor string, tempC float64) {
+	events := append(o.thermal.stats.RecentEvents, model.ThermalEvent{
+		Time:   time.Now(),
+		Action: action,
+		Sensor: sensor,
+		TempC:  tempC,
+	})
+	if len(events) > maxRecentThermalEvents {
+		events = events[len(events)-maxRecentThermalEvents:]
+	}
+	o.thermal.stats.RecentEvents = events
+}
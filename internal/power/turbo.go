@@ -0,0 +1,149 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"os"
+)
+
+const (
+	perPolicyTurboKnob   = "per-policy"
+	intelPstateTurboKnob = "intel-pstate"
+	cpufreqTurboKnob     = "cpufreq-boost"
+	noTurboKnob          = "none"
+)
+
+type turboState struct {
+	knob       string
+	origValues map[string]string
+	capFMhz    map[string]uint
+}
+
+// detectTurboKnob finds the most fine-grained turbo control exposed by the cpufreq driver.
+func detectTurboKnob(coreId int) string {
+	if _, err := os.Stat(sysfs.CpuFile(coreId, sysfs.PolicyBoostFile)); err == nil {
+		return perPolicyTurboKnob
+	}
+	if _, err := os.Stat(sysfs.IntelPstateNoTurboPath); err == nil {
+		return intelPstateTurboKnob
+	}
+	if _, err := os.Stat(sysfs.CpufreqBoostPath); err == nil {
+		return cpufreqTurboKnob
+	}
+	return noTurboKnob
+}
+
+// readNominalFMhz reads the highest non-turbo frequency of a core.
+func readNominalFMhz(coreId int) (uint, error) {
+	baseFKHz, err := sysfs.ReadUint(sysfs.CpuFile(coreId, sysfs.BaseFrequencyFile))
+	if err == nil {
+		return baseFKHz / 1000, nil
+	}
+	nominalFMhz, err := sysfs.ReadUint(sysfs.CpuFile(coreId, sysfs.NominalFrequencyFile))
+	if err != nil {
+		return 0, fmt.Errorf("failed at reading the nominal frequency of core: %d: %w", coreId, err)
+	}
+	return nominalFMhz, nil
+}
+
+// setupTurbo allows or disallows turbo per pool. Per-policy boost knobs are set directly. Otherwise the system-wide
+// knob is used when pools agree, and pools disallowed to turbo are capped at the nominal frequency when they do not.
+func (o *SleepController) setupTurbo() error {
+	pools := map[string][]int{
+		StablePool:  o.sleepState.stableCpuIds,
+		DynamicPool: o.sleepState.dynamicCpuIds,
+	}
+	allowed := map[string]bool{
+		StablePool:  o.conf.PowerProfile.StableTurbo,
+		DynamicPool: o.conf.PowerProfile.DynamicTurbo,
+	}
+	managedCpuIds := append(append([]int{}, o.sleepState.stableCpuIds...), o.sleepState.dynamicCpuIds...)
+	if len(managedCpuIds) == 0 {
+		return nil
+	}
+	o.turbo = turboState{
+		knob:       detectTurboKnob(managedCpuIds[0]),
+		origValues: map[string]string{},
+		capFMhz:    map[string]uint{},
+	}
+	if allowed[StablePool] && allowed[DynamicPool] {
+		// turbo is left to the admin, such that a system-wide no_turbo is not overridden.
+		log.Println("both pools are allowed to turbo, leaving turbo knobs untouched")
+		return nil
+	}
+	log.Printf("controlling turbo via %s knob. stable pool: %t, dynamic pool: %t", o.turbo.knob,
+		allowed[StablePool], allowed[DynamicPool])
+
+	switch o.turbo.knob {
+	case perPolicyTurboKnob:
+		for poolName, coreIds := range pools {
+			for _, coreId := range coreIds {
+				err := o.writeTurboKnob(sysfs.CpuFile(coreId, sysfs.PolicyBoostFile), boolKnob(allowed[poolName]))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case intelPstateTurboKnob, cpufreqTurboKnob:
+		if allowed[StablePool] == allowed[DynamicPool] {
+			return o.writeSystemTurboKnob(allowed[StablePool])
+		}
+		err := o.writeSystemTurboKnob(true)
+		if err != nil {
+			return err
+		}
+	}
+
+	for poolName, coreIds := range pools {
+		if allowed[poolName] || len(coreIds) == 0 {
+			continue
+		}
+		nominalFMhz, err := readNominalFMhz(coreIds[0])
+		if err != nil {
+			log.Printf("unable to cap pool: %s below the turbo range: %v", poolName, err)
+			continue
+		}
+		log.Printf("capping pool: %s at nominal frequency: %d MHz to disallow turbo", poolName, nominalFMhz)
+		o.turbo.capFMhz[poolName] = nominalFMhz
+	}
+	return nil
+}
+
+func (o *SleepController) writeSystemTurboKnob(enabled bool) error {
+	if o.turbo.knob == intelPstateTurboKnob {
+		return o.writeTurboKnob(sysfs.IntelPstateNoTurboPath, boolKnob(!enabled))
+	}
+	return o.writeTurboKnob(sysfs.CpufreqBoostPath, boolKnob(enabled))
+}
+
+// writeTurboKnob writes a turbo knob, remembering its original value to restore upon clean up.
+func (o *SleepController) writeTurboKnob(path string, value string) error {
+	if _, saved := o.turbo.origValues[path]; !saved {
+		orig, err := sysfs.ReadString(path)
+		if err != nil {
+			return err
+		}
+		o.turbo.origValues[path] = orig
+	}
+	return sysfs.WriteString(path, value)
+}
+
+func (o *SleepController) restoreTurbo() error {
+	for path, value := range o.turbo.origValues {
+		err := sysfs.WriteString(path, value)
+		if err != nil {
+			return fmt.Errorf("failed at restoring turbo: %w", err)
+		}
+	}
+	o.turbo.origValues = map[string]string{}
+	return nil
+}
+
+func boolKnob(enabled bool) string {
+	if enabled {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,115 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"path/filepath"
+)
+
+const (
+	uncoreMinFreqFile = "min_freq_khz"
+	uncoreMaxFreqFile = "max_freq_khz"
+)
+
+type uncoreState struct {
+	isAsleep   bool
+	origLimits map[string][2]uint
+}
+
+// uncoreDieDirs lists the intel_uncore_frequency sysfs dirs of every die of a package.
+func uncoreDieDirs(packageId int) ([]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(sysfs.UncorePath, fmt.Sprintf("package_%02d_die_*", packageId)))
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing uncore dies of package: %d: %w", packageId, err)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("uncore frequency control is not available for package: %d. is intel_uncore_frequency "+
+			"module loaded?", packageId)
+	}
+	return dirs, nil
+}
+
+// setUncore applies the sleep or perf uncore frequencies of every configured package.
+func (o *SleepController) setUncore(asleep bool) error {
+	if o.uncore.origLimits == nil {
+		o.uncore.origLimits = map[string][2]uint{}
+	}
+	for _, profile := range o.conf.PowerProfile.Uncore {
+		minFMhz, maxFMhz := profile.PerfMinFrq, profile.PerfMaxFrq
+		if asleep {
+			minFMhz, maxFMhz = profile.SleepMinFrq, profile.SleepMaxFrq
+		}
+		dirs, err := uncoreDieDirs(profile.Package)
+		if err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			err = o.writeUncoreLimits(dir, minFMhz, maxFMhz)
+			if err != nil {
+				return err
+			}
+		}
+		log.Printf("uncore frequency of package: %d set to %d-%d MHz", profile.Package, minFMhz, maxFMhz)
+	}
+	o.uncore.isAsleep = asleep
+	return nil
+}
+
+// writeUncoreLimits writes the uncore frequency limits of a die and reads them back.
+func (o *SleepController) writeUncoreLimits(dir string, minFMhz int, maxFMhz int) error {
+	if minFMhz > maxFMhz {
+		return fmt.Errorf("uncore max frequency can't be lower than min")
+	}
+	minPath, maxPath := filepath.Join(dir, uncoreMinFreqFile), filepath.Join(dir, uncoreMaxFreqFile)
+	if _, saved := o.uncore.origLimits[dir]; !saved {
+		origMinFKHz, err1 := sysfs.ReadUint(minPath)
+		origMaxFKHz, err2 := sysfs.ReadUint(maxPath)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("failed at reading uncore frequency: %w, %w", err1, err2)
+		}
+		o.uncore.origLimits[dir] = [2]uint{origMinFKHz, origMaxFKHz}
+	}
+
+	expected := map[string]uint{minPath: uint(minFMhz) * 1000, maxPath: uint(maxFMhz) * 1000}
+	err := sysfs.WriteLimits(minPath, maxPath, expected[minPath], expected[maxPath])
+	if err != nil {
+		return err
+	}
+	var diffs []model.CoreDiff
+	for _, path := range []string{minPath, maxPath} {
+		actual, err := sysfs.ReadUint(path)
+		if err != nil {
+			return err
+		}
+		if actual != expected[path] {
+			diffs = append(diffs, model.CoreDiff{CoreId: -1, Attribute: path, Expected: fmt.Sprint(expected[path]),
+				Actual: fmt.Sprint(actual)})
+		}
+	}
+	if len(diffs) > 0 {
+		return &VerificationError{PoolName: "uncore", Diffs: diffs}
+	}
+	return nil
+}
+
+// followSleepWithUncore lowers the uncore frequencies when dynamic cores sleep, and raises them upon wake.
+func (o *SleepController) followSleepWithUncore(asleep bool) error {
+	if !o.conf.PowerProfile.UncoreOnSleep || len(o.conf.PowerProfile.Uncore) == 0 || o.uncore.isAsleep == asleep {
+		return nil
+	}
+	return o.setUncore(asleep)
+}
+
+func (o *SleepController) restoreUncore() error {
+	for dir, limits := range o.uncore.origLimits {
+		err := sysfs.WriteLimits(filepath.Join(dir, uncoreMinFreqFile), filepath.Join(dir, uncoreMaxFreqFile),
+			limits[0], limits[1])
+		if err != nil {
+			return fmt.Errorf("failed at restoring uncore frequency: %w", err)
+		}
+	}
+	o.uncore.origLimits = nil
+	return nil
+}
@@ -0,0 +1,102 @@
+package power
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"log"
+	"strings"
+	"time"
+)
+
+// VerificationError is returned when cores do not reflect the power settings applied to them.
+type VerificationError struct {
+	PoolName string
+	Diffs    []model.CoreDiff
+}
+
+func (e *VerificationError) Error() string {
+	var diffs []string
+	for _, diff := range e.Diffs {
+		diffs = append(diffs, fmt.Sprintf("core %d %s: expected %s, got %s", diff.CoreId, diff.Attribute,
+			diff.Expected, diff.Actual))
+	}
+	return fmt.Sprintf("pool: %s did not reflect the requested settings: %s", e.PoolName, strings.Join(diffs, "; "))
+}
+
+// applyAndVerify runs apply and reads back the effective settings of the given cores. apply is repeated as per
+// the configured retry policy until the cores reflect the requested profile. Callers hold opMu and mu, and mu is
+// released while waiting between retries, such that status reads and background loops are not blocked meanwhile.
+func (o *SleepController) applyAndVerify(poolName string, coreIds []int, profile poolProfile,
+	apply func() error) error {
+	err := apply()
+	if err != nil || !o.conf.Verification.Enabled {
+		return err
+	}
+	diffs, err := o.verifyCores(poolName, coreIds, profile)
+	for retry := 0; err == nil && len(diffs) > 0 && retry < o.conf.Verification.RetryCount; retry++ {
+		log.Printf("pool: %s did not reflect the requested settings, retrying (%d/%d)...", poolName, retry+1,
+			o.conf.Verification.RetryCount)
+		o.mu.Unlock()
+		time.Sleep(time.Duration(o.conf.Verification.RetryIntervalMs) * time.Millisecond)
+		o.mu.Lock()
+		err = apply()
+		if err != nil {
+			return err
+		}
+		diffs, err = o.verifyCores(poolName, coreIds, profile)
+	}
+	if err != nil {
+		return fmt.Errorf("failed at verifying pool: %s settings: %w", poolName, err)
+	}
+	if len(diffs) > 0 {
+		return &VerificationError{PoolName: poolName, Diffs: diffs}
+	}
+	return nil
+}
+
+// verifyCores compares the effective idle states and frequency limits of the given cores with the profile.
+// Settings absent in the profile are not checked.
+func (o *SleepController) verifyCores(poolName string, coreIds []int, profile poolProfile) ([]model.CoreDiff, error) {
+	var diffs []model.CoreDiff
+	expMinFMhz, expMaxFMhz := o.frequencyRange(poolName, profile.fMhz)
+	expMinFKHz, expMaxFKHz := expMinFMhz*1000, expMaxFMhz*1000
+	for _, coreId := range coreIds {
+		if profile.fMhz > 0 {
+			minFKHz, maxFKHz, err := sysfs.ScalingLimits(coreId)
+			if err != nil {
+				return nil, err
+			}
+			if minFKHz != expMinFKHz {
+				diffs = append(diffs, newCoreDiff(coreId, "scaling_min_freq", expMinFKHz, minFKHz))
+			}
+			if maxFKHz != expMaxFKHz {
+				diffs = append(diffs, newCoreDiff(coreId, "scaling_max_freq", expMaxFKHz, maxFKHz))
+			}
+		}
+
+		if profile.idleState == "" {
+			continue
+		}
+		idleStates, err := sysfs.IdleStates(coreId)
+		if err != nil {
+			return nil, err
+		}
+		for state, enabled := range idleStates {
+			if enabled != (state == profile.idleState) {
+				diffs = append(diffs, newCoreDiff(coreId, "idle-state "+state+" enabled", state == profile.idleState,
+					enabled))
+			}
+		}
+	}
+	return diffs, nil
+}
+
+func newCoreDiff(coreId int, attribute string, expected any, actual any) model.CoreDiff {
+	return model.CoreDiff{
+		CoreId:    coreId,
+		Attribute: attribute,
+		Expected:  fmt.Sprint(expected),
+		Actual:    fmt.Sprint(actual),
+	}
+}
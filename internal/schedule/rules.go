@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/power"
+	"slices"
+	"time"
+)
+
+const (
+	SleepAction     = "sleep"
+	WakeAction      = "wake"
+	FrequencyAction = "frequency"
+	// SuspendAction is only valid for overrides, and suspends every rule for the override window.
+	SuspendAction = "suspend"
+
+	clockLayout = "15:04"
+)
+
+var dayNames = map[string][]time.Weekday{
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"sun":      {time.Sunday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// ValidateRule checks that a rule has a name, a known action on a known pool, known days and a HH:MM window.
+func ValidateRule(rule model.ScheduleRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("schedule rule must have a name")
+	}
+	err := validateAction(rule.Action, rule.FMhz, rule.Pool, false)
+	if err != nil {
+		return fmt.Errorf("invalid schedule rule: %s: %w", rule.Name, err)
+	}
+	for _, day := range rule.Days {
+		if _, found := dayNames[day]; !found {
+			return fmt.Errorf("invalid schedule rule: %s: unknown day: %s", rule.Name, day)
+		}
+	}
+	_, err1 := time.Parse(clockLayout, rule.Start)
+	_, err2 := time.Parse(clockLayout, rule.End)
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("invalid schedule rule: %s: start and end must be in HH:MM format", rule.Name)
+	}
+	if rule.Start == rule.End {
+		return fmt.Errorf("invalid schedule rule: %s: start and end can't be the same", rule.Name)
+	}
+	return nil
+}
+
+// ValidateOverride checks that an override has a known action on a known pool and a positive duration.
+func ValidateOverride(op model.ScheduleOverrideOp) error {
+	if op.DurationMin <= 0 {
+		return fmt.Errorf("override duration must be positive")
+	}
+	return validateAction(op.Action, op.FMhz, op.Pool, true)
+}
+
+func validateAction(action string, fMhz uint, pool string, isOverride bool) error {
+	if pool != "" && pool != power.DynamicPool && pool != power.StablePool {
+		return fmt.Errorf("unknown pool: %s", pool)
+	}
+	switch action {
+	case SleepAction, WakeAction:
+		if poolOf(pool) != power.DynamicPool {
+			return fmt.Errorf("%s action only applies to pool: %s", action, power.DynamicPool)
+		}
+		return nil
+	case FrequencyAction:
+		if fMhz == 0 {
+			return fmt.Errorf("frequency action needs f-mhz")
+		}
+		return nil
+	case SuspendAction:
+		if isOverride {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action: %s", action)
+}
+
+// isActive tells whether the rule window contains the given time. Windows ending before they start span midnight,
+// and then belong to the day they start on.
+func isActive(rule model.ScheduleRule, now time.Time) bool {
+	start, _ := time.Parse(clockLayout, rule.Start)
+	end, _ := time.Parse(clockLayout, rule.End)
+	startMin, endMin := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	nowMin := now.Hour()*60 + now.Minute()
+
+	if startMin < endMin {
+		return onDay(rule, now.Weekday()) && nowMin >= startMin && nowMin < endMin
+	}
+	yesterday := now.AddDate(0, 0, -1).Weekday()
+	return (onDay(rule, now.Weekday()) && nowMin >= startMin) || (onDay(rule, yesterday) && nowMin < endMin)
+}
+
+// poolOf returns the pool a rule applies to, which is the dynamic pool unless stated.
+func poolOf(pool string) string {
+	if pool == "" {
+		return power.DynamicPool
+	}
+	return pool
+}
+
+func onDay(rule model.ScheduleRule, day time.Weekday) bool {
+	if len(rule.Days) == 0 {
+		return true
+	}
+	for _, name := range rule.Days {
+		if slices.Contains(dayNames[name], day) {
+			return true
+		}
+	}
+	return false
+}
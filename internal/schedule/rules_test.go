@@ -0,0 +1,81 @@
+package schedule
+
+import (
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"testing"
+	"time"
+)
+
+func TestIsActive(t *testing.T) {
+	workHours := model.ScheduleRule{Days: []string{"weekdays"}, Start: "09:00", End: "17:00"}
+	weekdayNights := model.ScheduleRule{Days: []string{"weekdays"}, Start: "22:00", End: "06:00"}
+	everyNight := model.ScheduleRule{Start: "22:00", End: "06:00"}
+	// 2024-06-03 is a monday.
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		name string
+		rule model.ScheduleRule
+		now  time.Time
+		want bool
+	}{
+		{name: "within the window", rule: workHours, now: at(3, 10, 0), want: true},
+		{name: "at the start", rule: workHours, now: at(3, 9, 0), want: true},
+		{name: "at the end", rule: workHours, now: at(3, 17, 0), want: false},
+		{name: "before the window", rule: workHours, now: at(3, 8, 59), want: false},
+		{name: "on another day", rule: workHours, now: at(8, 10, 0), want: false},
+		{name: "overnight before midnight", rule: weekdayNights, now: at(3, 23, 0), want: true},
+		{name: "overnight after midnight", rule: weekdayNights, now: at(4, 5, 0), want: true},
+		{name: "overnight started on friday", rule: weekdayNights, now: at(8, 5, 0), want: true},
+		{name: "overnight started on saturday", rule: weekdayNights, now: at(9, 5, 0), want: false},
+		{name: "overnight started on sunday", rule: weekdayNights, now: at(3, 5, 0), want: false},
+		{name: "overnight on saturday", rule: weekdayNights, now: at(8, 23, 0), want: false},
+		{name: "overnight after its end", rule: weekdayNights, now: at(4, 6, 0), want: false},
+		{name: "every day", rule: everyNight, now: at(9, 5, 0), want: true},
+	}
+	for _, c := range cases {
+		got := isActive(c.rule, c.now)
+		if got != c.want {
+			t.Errorf("%s: isActive(%s-%s, %s) = %t, want %t", c.name, c.rule.Start, c.rule.End,
+				c.now.Format("Mon 15:04"), got, c.want)
+		}
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    model.ScheduleRule
+		wantErr bool
+	}{
+		{name: "sleep rule", rule: model.ScheduleRule{Name: "nights", Action: SleepAction, Days: []string{"weekdays"},
+			Start: "22:00", End: "06:00"}},
+		{name: "frequency rule on the stable pool", rule: model.ScheduleRule{Name: "eco", Action: FrequencyAction,
+			FMhz: 1200, Pool: "stbl-pool", Days: []string{"weekends"}, Start: "00:00", End: "23:59"}},
+		{name: "no name", rule: model.ScheduleRule{Action: SleepAction, Start: "22:00", End: "06:00"},
+			wantErr: true},
+		{name: "unknown action", rule: model.ScheduleRule{Name: "r", Action: "hibernate", Start: "22:00",
+			End: "06:00"}, wantErr: true},
+		{name: "suspend rule", rule: model.ScheduleRule{Name: "r", Action: SuspendAction, Start: "22:00",
+			End: "06:00"}, wantErr: true},
+		{name: "frequency without f-mhz", rule: model.ScheduleRule{Name: "r", Action: FrequencyAction,
+			Start: "22:00", End: "06:00"}, wantErr: true},
+		{name: "sleep on the stable pool", rule: model.ScheduleRule{Name: "r", Action: SleepAction,
+			Pool: "stbl-pool", Start: "22:00", End: "06:00"}, wantErr: true},
+		{name: "unknown pool", rule: model.ScheduleRule{Name: "r", Action: FrequencyAction, FMhz: 1200,
+			Pool: "gpu-pool", Start: "22:00", End: "06:00"}, wantErr: true},
+		{name: "unknown day", rule: model.ScheduleRule{Name: "r", Action: SleepAction, Days: []string{"holidays"},
+			Start: "22:00", End: "06:00"}, wantErr: true},
+		{name: "malformed window", rule: model.ScheduleRule{Name: "r", Action: SleepAction, Start: "10pm",
+			End: "06:00"}, wantErr: true},
+		{name: "empty window", rule: model.ScheduleRule{Name: "r", Action: SleepAction, Start: "06:00",
+			End: "06:00"}, wantErr: true},
+	}
+	for _, c := range cases {
+		err := ValidateRule(c.rule)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: ValidateRule() = %v, want an error: %t", c.name, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/power"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/state"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	ConfigSource = "config"
+	APISource    = "api"
+
+	checkInterval = 30 * time.Second
+	stateName     = "schedules"
+)
+
+var (
+	ErrRuleExists   = errors.New("a schedule rule with the same name exists")
+	ErrRuleNotFound = errors.New("schedule rule not found")
+	ErrConfigRule   = errors.New("schedule rules from the config file can't be removed via the api")
+)
+
+// persistedState is what survives restarts. Rules from the config file are not persisted, since they are re-read upon
+// startup.
+type persistedState struct {
+	Rules    []model.ScheduleRule    `json:"rules"`
+	Override *model.ScheduleOverride `json:"override,omitempty"`
+}
+
+// Scheduler sleeps, wakes and changes the frequency of pools as per time windows. Upon leaving a window, the change
+// is reverted (ex: dynamic cores are woken after a sleep window, and put back to sleep after a wake window).
+type Scheduler struct {
+	controller  *power.SleepController
+	perfFMhz    uint
+	store       *state.Store
+	mu          sync.Mutex
+	configRules []model.ScheduleRule
+	apiRules    []model.ScheduleRule
+	override    *model.ScheduleOverride
+	activeRule  string
+	applied     *model.ScheduleRule
+	refresh     chan struct{}
+	stop        chan struct{}
+	exited      chan struct{}
+}
+
+func NewScheduler(controller *power.SleepController, conf *model.ConfYaml) (*Scheduler, error) {
+	scheduler := &Scheduler{
+		controller: controller,
+		perfFMhz:   uint(conf.PowerProfile.PerfFrq),
+		store:      state.NewStore(conf.Host.StateDir),
+		refresh:    make(chan struct{}, 1),
+	}
+	for _, rule := range conf.Schedules {
+		err := ValidateRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		rule.Source = ConfigSource
+		scheduler.configRules = append(scheduler.configRules, rule)
+	}
+
+	var persisted persistedState
+	found, err := scheduler.store.Load(stateName, &persisted)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		scheduler.apiRules, scheduler.override = persisted.Rules, persisted.Override
+		log.Printf("restored %d schedule rules", len(persisted.Rules))
+	}
+	return scheduler, nil
+}
+
+func (s *Scheduler) Start() {
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.exited = make(chan struct{})
+	log.Printf("starting scheduler with %d rules", len(s.configRules)+len(s.apiRules))
+	go func(stop chan struct{}, exited chan struct{}) {
+		defer close(exited)
+		s.check()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.check()
+			case <-s.refresh:
+				s.check()
+			}
+		}
+	}(s.stop, s.exited)
+}
+
+// Stop stops checking the rules, and waits for a check in progress, such that no action follows.
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.exited
+	s.stop = nil
+}
+
+// Status lists every rule along with the override and the rule in effect.
+func (s *Scheduler) Status() model.ScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := model.ScheduleStatus{
+		Rules:      append(append([]model.ScheduleRule{}, s.configRules...), s.apiRules...),
+		Override:   s.override,
+		ActiveRule: s.activeRule,
+	}
+	if s.applied != nil {
+		status.AppliedAction = s.applied.Action
+	}
+	return status
+}
+
+// AddRule adds a rule and persists it, such that it survives restarts.
+func (s *Scheduler) AddRule(rule model.ScheduleRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.rules(), func(r model.ScheduleRule) bool { return r.Name == rule.Name }) {
+		return ErrRuleExists
+	}
+	s.apiRules = append(s.apiRules, rule)
+	return s.persist()
+}
+
+func (s *Scheduler) RemoveRule(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if slices.ContainsFunc(s.configRules, func(r model.ScheduleRule) bool { return r.Name == name }) {
+		return ErrConfigRule
+	}
+	idx := slices.IndexFunc(s.apiRules, func(r model.ScheduleRule) bool { return r.Name == name })
+	if idx < 0 {
+		return ErrRuleNotFound
+	}
+	s.apiRules = slices.Delete(s.apiRules, idx, idx+1)
+	return s.persist()
+}
+
+// SetOverride applies the given action instead of the rules for the given duration.
+func (s *Scheduler) SetOverride(op model.ScheduleOverrideOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.override = &model.ScheduleOverride{
+		Action: op.Action,
+		FMhz:   op.FMhz,
+		Pool:   op.Pool,
+		Until:  time.Now().Add(time.Duration(op.DurationMin) * time.Minute),
+	}
+	return s.persist()
+}
+
+func (s *Scheduler) ClearOverride() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.override = nil
+	return s.persist()
+}
+
+// Refresh re-evaluates the rules right away, instead of waiting for the next check. The check runs on the scheduler
+// loop, such that none follows Stop.
+func (s *Scheduler) Refresh() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+		// a refresh is already pending.
+	}
+}
+
+func (s *Scheduler) rules() []model.ScheduleRule {
+	return append(append([]model.ScheduleRule{}, s.configRules...), s.apiRules...)
+}
+
+func (s *Scheduler) persist() error {
+	return s.store.Save(stateName, persistedState{Rules: s.apiRules, Override: s.override})
+}
+
+// desiredRule returns the override or the first rule in effect at the given time, or nil if none.
+func (s *Scheduler) desiredRule(now time.Time) *model.ScheduleRule {
+	if s.override != nil && now.After(s.override.Until) {
+		log.Printf("schedule override: %s expired", s.override.Action)
+		s.override = nil
+		err := s.persist()
+		if err != nil {
+			log.Printf("unable to persist expired override: %v", err)
+		}
+	}
+	if s.override != nil {
+		if s.override.Action == SuspendAction {
+			return nil
+		}
+		return &model.ScheduleRule{Name: "override", Action: s.override.Action, FMhz: s.override.FMhz,
+			Pool: s.override.Pool}
+	}
+	for _, rule := range s.rules() {
+		if isActive(rule, now) {
+			return &rule
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) check() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	desired := s.desiredRule(time.Now())
+	s.activeRule = ""
+	if desired != nil {
+		s.activeRule = desired.Name
+	}
+	if sameAction(desired, s.applied) {
+		return
+	}
+
+	if s.applied != nil && !supersedes(desired, s.applied) {
+		log.Printf("schedule rule: %s is no longer in effect, reverting %s of pool: %s", s.applied.Name,
+			s.applied.Action, poolOf(s.applied.Pool))
+		err := s.revert(s.applied)
+		if err != nil {
+			log.Printf("failed at reverting schedule: %v", err)
+			return
+		}
+		s.applied = nil
+	}
+	if desired != nil {
+		log.Printf("schedule rule: %s is in effect, applying %s to pool: %s", desired.Name, desired.Action,
+			poolOf(desired.Pool))
+		err := s.apply(desired)
+		if err != nil {
+			log.Printf("failed at applying schedule: %v", err)
+			return
+		}
+	}
+	s.applied = desired
+}
+
+func (s *Scheduler) apply(rule *model.ScheduleRule) error {
+	switch rule.Action {
+	case SleepAction:
+		return s.controller.SleepUnlessHeld()
+	case WakeAction:
+		return s.controller.Wake()
+	case FrequencyAction:
+		return s.controller.OpPoolFrequency(poolOf(rule.Pool), rule.FMhz)
+	}
+	return fmt.Errorf("unknown action: %s", rule.Action)
+}
+
+// revert undoes the action of a rule which is no longer in effect.
+func (s *Scheduler) revert(rule *model.ScheduleRule) error {
+	switch rule.Action {
+	case SleepAction:
+		return s.controller.Wake()
+	case WakeAction:
+		return s.controller.SleepUnlessHeld()
+	case FrequencyAction:
+		return s.controller.OpPoolFrequency(poolOf(rule.Pool), s.perfFMhz)
+	}
+	return fmt.Errorf("unknown action: %s", rule.Action)
+}
+
+// supersedes tells whether applying the desired rule replaces the applied one, such that the applied one needs no
+// revert. This is the case when both set the same setting of the same pool.
+func supersedes(desired *model.ScheduleRule, applied *model.ScheduleRule) bool {
+	if desired == nil || poolOf(desired.Pool) != poolOf(applied.Pool) {
+		return false
+	}
+	return (desired.Action == FrequencyAction) == (applied.Action == FrequencyAction)
+}
+
+func sameAction(a *model.ScheduleRule, b *model.ScheduleRule) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Action == b.Action && a.FMhz == b.FMhz && poolOf(a.Pool) == poolOf(b.Pool)
+}
@@ -1,6 +1,7 @@
 package serviceerror
 
 import (
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"log"
@@ -15,6 +16,11 @@ func ErrorHandler() gin.HandlerFunc {
 		for _, err := range c.Errors {
 			id := uuid.New()
 			log.Println(fmt.Sprintf("error id: %s - ", id.String()), err)
+			var httpErr Http
+			if errors.As(err.Err, &httpErr) {
+				c.AbortWithStatusJSON(httpErr.StatusCode, httpErr)
+				continue
+			}
 			c.AbortWithStatusJSON(http.StatusInternalServerError, map[string]string{"message": fmt.Sprintf("Something went wrong. Check server logs for id: %s.", id.String())})
 		}
 	}
@@ -0,0 +1,82 @@
+package state
+
+import (
+	"slices"
+	"time"
+)
+
+// Records is a keyed collection of records of a controller component (ex: wake leases by id), which is persisted in a
+// Store as a list ordered by creation. Records is not safe for concurrent use, and a nil Records holds nothing.
+type Records[T any] struct {
+	store   *Store
+	name    string
+	key     func(T) string
+	created func(T) time.Time
+	records map[string]T
+}
+
+func NewRecords[T any](store *Store, name string, key func(T) string, created func(T) time.Time) *Records[T] {
+	return &Records[T]{store: store, name: name, key: key, created: created, records: map[string]T{}}
+}
+
+// Load replaces the records with the persisted ones, and returns how many were restored.
+func (r *Records[T]) Load() (int, error) {
+	var persisted []T
+	_, err := r.store.Load(r.name, &persisted)
+	if err != nil {
+		return 0, err
+	}
+	r.records = map[string]T{}
+	for _, record := range persisted {
+		r.records[r.key(record)] = record
+	}
+	return len(r.records), nil
+}
+
+// Save persists the records, replacing the previously saved ones.
+func (r *Records[T]) Save() error {
+	return r.store.Save(r.name, r.List())
+}
+
+func (r *Records[T]) Get(key string) (T, bool) {
+	if r == nil {
+		var zero T
+		return zero, false
+	}
+	record, found := r.records[key]
+	return record, found
+}
+
+// Put adds a record, replacing the one of the same key.
+func (r *Records[T]) Put(record T) {
+	r.records[r.key(record)] = record
+}
+
+// Delete drops the record of the given key, and returns false if there was none.
+func (r *Records[T]) Delete(key string) bool {
+	if _, found := r.Get(key); !found {
+		return false
+	}
+	delete(r.records, key)
+	return true
+}
+
+func (r *Records[T]) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.records)
+}
+
+// List returns the records in the order they were created.
+func (r *Records[T]) List() []T {
+	records := []T{}
+	if r == nil {
+		return records
+	}
+	for _, record := range r.records {
+		records = append(records, record)
+	}
+	slices.SortFunc(records, func(a, b T) int { return r.created(a).Compare(r.created(b)) })
+	return records
+}
@@ -0,0 +1,65 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultStateDir is on persistent storage, unlike the runtime dir which is usually cleared upon reboot.
+const DefaultStateDir = "/var/lib/gc-controller"
+
+// Store persists the runtime state of controller components as json files in the state dir, such that it survives
+// restarts and reboots.
+type Store struct {
+	dir string
+}
+
+func NewStore(stateDir string) *Store {
+	if stateDir == "" {
+		stateDir = DefaultStateDir
+	}
+	return &Store{dir: stateDir}
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// Load reads the state of the given name into v, and returns false if no state was saved before.
+func (s *Store) Load(name string, v any) (bool, error) {
+	content, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed at reading state: %s: %w", s.path(name), err)
+	}
+	err = json.Unmarshal(content, v)
+	if err != nil {
+		return false, fmt.Errorf("failed at parsing state: %s: %w", s.path(name), err)
+	}
+	return true, nil
+}
+
+// Save replaces the state of the given name with v.
+func (s *Store) Save(name string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed at serializing state: %s: %w", name, err)
+	}
+	err = os.MkdirAll(s.dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed at creating state dir: %s: %w", s.dir, err)
+	}
+	tmpPath := s.path(name) + ".tmp"
+	err = os.WriteFile(tmpPath, content, 0644)
+	if err == nil {
+		err = os.Rename(tmpPath, s.path(name))
+	}
+	if err != nil {
+		return fmt.Errorf("failed at persisting state: %s: %w", s.path(name), err)
+	}
+	return nil
+}
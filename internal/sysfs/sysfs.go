@@ -0,0 +1,391 @@
+package sysfs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	CpuPath  = "/sys/devices/system/cpu"
+	NodePath = "/sys/devices/system/node"
+
+	OnlineFile        = "online"
+	ResumeLatencyFile = "power/pm_qos_resume_latency_us"
+	// UnconstrainedResumeLatency lifts the resume latency constraint of a cpu, whereas "n/a" is the strictest one,
+	// limiting the cpu to polling.
+	UnconstrainedResumeLatency = "0"
+	CpuDmaLatencyDevPath       = "/dev/cpu_dma_latency"
+
+	IdleDriverFile                = "cpuidle/current_driver"
+	ScalingDriverFile             = "cpufreq/scaling_driver"
+	ScalingGovernorFile           = "cpufreq/scaling_governor"
+	ScalingAvailableGovernorsFile = "cpufreq/scaling_available_governors"
+	ScalingMinFreqFile            = "cpufreq/scaling_min_freq"
+	ScalingMaxFreqFile            = "cpufreq/scaling_max_freq"
+	CpuInfoMinFreqFile            = "cpufreq/cpuinfo_min_freq"
+	CpuInfoMaxFreqFile            = "cpufreq/cpuinfo_max_freq"
+	BaseFrequencyFile             = "cpufreq/base_frequency"
+	NominalFrequencyFile          = "acpi_cppc/nominal_freq"
+	PolicyBoostFile               = "cpufreq/boost"
+	IntelPstateNoTurboPath        = CpuPath + "/intel_pstate/no_turbo"
+	CpufreqBoostPath              = CpuPath + "/cpufreq/boost"
+	UncorePath                    = CpuPath + "/intel_uncore_frequency"
+	IdleGovernorPath              = CpuPath + "/cpuidle/current_governor"
+	IdleGovernorReadOnlyPath      = CpuPath + "/cpuidle/current_governor_ro"
+	AvailableIdleGovernorsPath    = CpuPath + "/cpuidle/available_governors"
+
+	ThermalPath   = "/sys/class/thermal"
+	HwmonPath     = "/sys/class/hwmon"
+	CoretempHwmon = "coretemp"
+
+	PowercapPath        = "/sys/class/powercap"
+	EnergyFile          = "energy_uj"
+	MaxEnergyRangeFile  = "max_energy_range_uj"
+	RaplPowerLimitFile  = "constraint_0_power_limit_uw"
+	raplPackageZonePref = "package-"
+
+	ProcStatPath = "/proc/stat"
+
+	cpuIdleStateDirFmt = "cpuidle/state%d"
+)
+
+// CpuTime is the time a cpu spent in each state since boot, in jiffies. Guest time is part of user time.
+type CpuTime struct {
+	User    uint64
+	Nice    uint64
+	System  uint64
+	Idle    uint64
+	Iowait  uint64
+	Irq     uint64
+	Softirq uint64
+	Steal   uint64
+}
+
+func (t CpuTime) Total() uint64 {
+	return t.User + t.Nice + t.System + t.Idle + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// CpuFile returns the sysfs path of a per-cpu attribute.
+func CpuFile(cpuId int, file string) string {
+	return filepath.Join(CpuPath, fmt.Sprint("cpu", cpuId), file)
+}
+
+func ReadString(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed at reading %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+func ReadUint(path string) (uint, error) {
+	content, err := ReadString(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(content, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed at parsing %s: %w", path, err)
+	}
+	return uint(value), nil
+}
+
+func WriteString(path string, value string) error {
+	err := os.WriteFile(path, []byte(value), 0644)
+	if err != nil {
+		return fmt.Errorf("failed at writing %s to %s: %w", value, path, err)
+	}
+	return nil
+}
+
+// WriteLimits writes a pair of min and max limits in an order that never makes min exceed max.
+func WriteLimits(minPath string, maxPath string, minValue uint, maxValue uint) error {
+	curMax, err := ReadUint(maxPath)
+	if err != nil {
+		return err
+	}
+	if minValue > curMax {
+		err = WriteString(maxPath, strconv.Itoa(int(maxValue)))
+		if err != nil {
+			return err
+		}
+		return WriteString(minPath, strconv.Itoa(int(minValue)))
+	}
+	err = WriteString(minPath, strconv.Itoa(int(minValue)))
+	if err != nil {
+		return err
+	}
+	return WriteString(maxPath, strconv.Itoa(int(maxValue)))
+}
+
+// CpuIds lists the ids of all cpus known to the kernel, including offline ones.
+func CpuIds() ([]int, error) {
+	dirs, err := filepath.Glob(filepath.Join(CpuPath, "cpu[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing cpus: %w", err)
+	}
+	var ids []int
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "cpu"))
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// IdleStateNames lists the idle states of a cpu in the order of their depth.
+func IdleStateNames(cpuId int) ([]string, error) {
+	var names []string
+	for i := 0; ; i++ {
+		stateDir := CpuFile(cpuId, fmt.Sprintf(cpuIdleStateDirFmt, i))
+		if _, err := os.Stat(stateDir); os.IsNotExist(err) {
+			break
+		}
+		name, err := ReadString(filepath.Join(stateDir, "name"))
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// IdleStates returns the idle states of a cpu mapped to whether each state is enabled.
+func IdleStates(cpuId int) (map[string]bool, error) {
+	names, err := IdleStateNames(cpuId)
+	if err != nil {
+		return nil, err
+	}
+	states := map[string]bool{}
+	for i, name := range names {
+		disabled, err := ReadUint(IdleStateFile(cpuId, i, "disable"))
+		if err != nil {
+			return nil, err
+		}
+		states[name] = disabled == 0
+	}
+	return states, nil
+}
+
+// IdleStateFile returns the sysfs path of an attribute of the idle state at the given index.
+func IdleStateFile(cpuId int, stateIndex int, file string) string {
+	return filepath.Join(CpuFile(cpuId, fmt.Sprintf(cpuIdleStateDirFmt, stateIndex)), file)
+}
+
+// ScalingLimits returns the effective min and max scaling frequencies of a cpu in KHz.
+func ScalingLimits(cpuId int) (uint, uint, error) {
+	minFKHz, err := ReadUint(CpuFile(cpuId, ScalingMinFreqFile))
+	if err != nil {
+		return 0, 0, err
+	}
+	maxFKHz, err := ReadUint(CpuFile(cpuId, ScalingMaxFreqFile))
+	if err != nil {
+		return 0, 0, err
+	}
+	return minFKHz, maxFKHz, nil
+}
+
+// ParseCpuList parses a kernel or libvirt cpu list (ex: 0-3,8,10-11 or 0-5,^3) into cpu ids. Ids prefixed with ^ are
+// excluded from the rest of the list, regardless of their position.
+func ParseCpuList(cpuList string) ([]int, error) {
+	var ids []int
+	var excludedIds []int
+	for _, part := range strings.Split(strings.TrimSpace(cpuList), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		excluded, isExcluded := strings.CutPrefix(part, "^")
+		if isExcluded {
+			id, err := strconv.Atoi(excluded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu list: %s: %w", cpuList, err)
+			}
+			excludedIds = append(excludedIds, id)
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpu list: %s: %w", cpuList, err)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cpu list: %s: %w", cpuList, err)
+			}
+			if end < start {
+				return nil, fmt.Errorf("invalid cpu list: %s: range %s is reversed", cpuList, part)
+			}
+		}
+		for id := start; id <= end; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return slices.DeleteFunc(ids, func(id int) bool { return slices.Contains(excludedIds, id) }), nil
+}
+
+// FormatCpuList formats cpu ids into a kernel cpu list, collapsing consecutive ids into ranges.
+func FormatCpuList(ids []int) string {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// OnlineCpuIds lists the ids of online cpus.
+func OnlineCpuIds() ([]int, error) {
+	cpuList, err := ReadString(filepath.Join(CpuPath, OnlineFile))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCpuList(cpuList)
+}
+
+// NodeCpuIds maps each numa node to the ids of its cpus.
+func NodeCpuIds() (map[int][]int, error) {
+	dirs, err := filepath.Glob(filepath.Join(NodePath, "node[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing numa nodes: %w", err)
+	}
+	nodes := map[int][]int{}
+	for _, dir := range dirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		cpuList, err := ReadString(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		nodes[node], err = ParseCpuList(cpuList)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// Temperatures reads every thermal zone, keyed by its type, and every coretemp hwmon sensor, keyed by its label, in
+// degrees Celsius. Sensors sharing a type or a label (ex: the cores of each socket) are keyed by the hottest one.
+func Temperatures() (map[string]float64, error) {
+	temps := map[string]float64{}
+	zones, err := filepath.Glob(filepath.Join(ThermalPath, "thermal_zone[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing thermal zones: %w", err)
+	}
+	for _, zone := range zones {
+		zoneType, err1 := ReadString(filepath.Join(zone, "type"))
+		milliC, err2 := ReadUint(filepath.Join(zone, "temp"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		temps[zoneType] = max(temps[zoneType], float64(milliC)/1000)
+	}
+
+	hwmons, err := filepath.Glob(filepath.Join(HwmonPath, "hwmon[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing hwmon sensors: %w", err)
+	}
+	for _, hwmon := range hwmons {
+		name, err := ReadString(filepath.Join(hwmon, "name"))
+		if err != nil || name != CoretempHwmon {
+			continue
+		}
+		inputs, err := filepath.Glob(filepath.Join(hwmon, "temp[0-9]*_input"))
+		if err != nil {
+			continue
+		}
+		for _, input := range inputs {
+			milliC, err := ReadUint(input)
+			if err != nil {
+				continue
+			}
+			label, err := ReadString(strings.TrimSuffix(input, "_input") + "_label")
+			if err != nil {
+				label = strings.TrimSuffix(filepath.Base(input), "_input")
+			}
+			temps[label] = max(temps[label], float64(milliC)/1000)
+		}
+	}
+	if len(temps) == 0 {
+		return nil, fmt.Errorf("no thermal zones nor coretemp sensors are exposed by the platform")
+	}
+	return temps, nil
+}
+
+// RaplPackageZones maps the name of each RAPL package zone (ex: package-0) to its powercap dir.
+func RaplPackageZones() (map[string]string, error) {
+	dirs, err := filepath.Glob(filepath.Join(PowercapPath, "intel-rapl:[0-9]*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed at listing rapl zones: %w", err)
+	}
+	zones := map[string]string{}
+	for _, dir := range dirs {
+		name, err := ReadString(filepath.Join(dir, "name"))
+		if err != nil || !strings.HasPrefix(name, raplPackageZonePref) {
+			continue
+		}
+		zones[name] = dir
+	}
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("no rapl package zones are exposed by the platform. is intel_rapl module loaded?")
+	}
+	return zones, nil
+}
+
+// CpuTimes reads the time each online cpu spent in each state from /proc/stat.
+func CpuTimes() (map[int]CpuTime, error) {
+	content, err := os.ReadFile(ProcStatPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed at reading %s: %w", ProcStatPath, err)
+	}
+	return parseCpuTimes(string(content))
+}
+
+// parseCpuTimes parses the per cpu lines of /proc/stat, skipping the other lines.
+func parseCpuTimes(content string) (map[int]CpuTime, error) {
+	times := map[int]CpuTime{}
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		// the first line is the sum over all cpus, labeled as cpu without an id.
+		if len(fields) < 9 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(fields[0], "cpu"))
+		if err != nil {
+			return nil, fmt.Errorf("failed at parsing %s: %w", ProcStatPath, err)
+		}
+		var values [8]uint64
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed at parsing %s: %w", ProcStatPath, err)
+			}
+		}
+		times[id] = CpuTime{User: values[0], Nice: values[1], System: values[2], Idle: values[3], Iowait: values[4],
+			Irq: values[5], Softirq: values[6], Steal: values[7]}
+	}
+	return times, nil
+}
@@ -0,0 +1,82 @@
+package sysfs
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseCpuList(t *testing.T) {
+	cases := []struct {
+		cpuList string
+		want    []int
+	}{
+		{cpuList: "", want: nil},
+		{cpuList: "0-3,8,10-11\n", want: []int{0, 1, 2, 3, 8, 10, 11}},
+		{cpuList: "0-5,^3", want: []int{0, 1, 2, 4, 5}},
+		{cpuList: "^1,0-3,^2", want: []int{0, 3}},
+		{cpuList: "4,^4", want: []int{}},
+	}
+	for _, c := range cases {
+		got, err := ParseCpuList(c.cpuList)
+		if err != nil {
+			t.Errorf("ParseCpuList(%q) failed: %v", c.cpuList, err)
+			continue
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("ParseCpuList(%q) = %v, want %v", c.cpuList, got, c.want)
+		}
+	}
+
+	for _, cpuList := range []string{"a", "0-b", "^x", "3-1", "^1-3"} {
+		_, err := ParseCpuList(cpuList)
+		if err == nil {
+			t.Errorf("ParseCpuList(%q) succeeded, want an error", cpuList)
+		}
+	}
+}
+
+func TestParseCpuTimes(t *testing.T) {
+	content := "cpu  100 1 50 1000 10 2 5 7 0 0\n" +
+		"cpu0 60 0 30 500 5 0 3 0 0 0\n" +
+		"cpu3 40 1 20 500 5 2 2 7 0 0\n" +
+		"intr 12345 0 0\n" +
+		"ctxt 999\n"
+	got, err := parseCpuTimes(content)
+	if err != nil {
+		t.Fatalf("parseCpuTimes() failed: %v", err)
+	}
+	want := map[int]CpuTime{
+		0: {User: 60, System: 30, Idle: 500, Iowait: 5, Softirq: 3},
+		3: {User: 40, Nice: 1, System: 20, Idle: 500, Iowait: 5, Irq: 2, Softirq: 2, Steal: 7},
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("parseCpuTimes() = %v, want %v", got, want)
+	}
+
+	for _, line := range []string{"cpu1 60 0 30 x 5 0 3 0 0 0", "cpux 60 0 30 500 5 0 3 0 0 0"} {
+		_, err := parseCpuTimes(line)
+		if err == nil {
+			t.Errorf("parseCpuTimes(%q) succeeded, want an error", line)
+		}
+	}
+}
+
+func TestFormatCpuList(t *testing.T) {
+	cases := []struct {
+		ids  []int
+		want string
+	}{
+		{ids: nil, want: ""},
+		{ids: []int{4}, want: "4"},
+		{ids: []int{0, 1, 2, 3, 8, 10, 11}, want: "0-3,8,10-11"},
+		{ids: []int{11, 3, 10, 2, 3}, want: "2-3,10-11"},
+		{ids: []int{1, 3, 5}, want: "1,3,5"},
+	}
+	for _, c := range cases {
+		got := FormatCpuList(c.ids)
+		if got != c.want {
+			t.Errorf("FormatCpuList(%v) = %q, want %q", c.ids, got, c.want)
+		}
+	}
+}
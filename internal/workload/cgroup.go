@@ -0,0 +1,119 @@
+package workload
+
+import (
+	"errors"
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	CgroupPath              = "/sys/fs/cgroup"
+	CpusetCpusFile          = "cpuset.cpus"
+	CpusetCpusEffectiveFile = "cpuset.cpus.effective"
+	CpuStatFile             = "cpu.stat"
+
+	usageUsecKey = "usage_usec"
+)
+
+// Cgroup is a cgroup v2 group along with its cpuset and cpu usage. Cpus is empty unless the group restricts its cpus
+// itself. Leaf groups have no child groups with the cpuset controller (ex: the scope of a container).
+type Cgroup struct {
+	Path          string
+	Cpus          []int
+	EffectiveCpus []int
+	UsageUsec     uint64
+	IsLeaf        bool
+}
+
+// CgroupRoot resolves a cgroup root, which is either an absolute path or relative to the cgroup v2 mount
+// (ex: kubepods.slice).
+func CgroupRoot(root string) string {
+	if filepath.IsAbs(root) {
+		return root
+	}
+	return filepath.Join(CgroupPath, root)
+}
+
+// Cgroups walks the cgroups under the given root, parents first. Groups without the cpuset controller are skipped.
+func Cgroups(root string) ([]Cgroup, error) {
+	var cgroups []Cgroup
+	err := filepath.WalkDir(CgroupRoot(root), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		effective, err := os.ReadFile(filepath.Join(path, CpusetCpusEffectiveFile))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		cgroup := Cgroup{Path: path}
+		cgroup.EffectiveCpus, err = sysfs.ParseCpuList(string(effective))
+		if err != nil {
+			return err
+		}
+		cpus, err := os.ReadFile(filepath.Join(path, CpusetCpusFile))
+		if err == nil {
+			cgroup.Cpus, err = sysfs.ParseCpuList(string(cpus))
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		cgroup.UsageUsec, err = readUsageUsec(path)
+		if err != nil {
+			return err
+		}
+		cgroups = append(cgroups, cgroup)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed at walking cgroups under %s: %w", root, err)
+	}
+	for i := range cgroups {
+		cgroups[i].IsLeaf = !slices.ContainsFunc(cgroups, func(child Cgroup) bool {
+			return strings.HasPrefix(child.Path, cgroups[i].Path+"/")
+		})
+	}
+	return cgroups, nil
+}
+
+// readUsageUsec reads the total cpu time of a cgroup, or zero if the cpu controller is not enabled for it.
+func readUsageUsec(path string) (uint64, error) {
+	content, err := os.ReadFile(filepath.Join(path, CpuStatFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		if key == usageUsecKey {
+			return strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		}
+	}
+	return 0, nil
+}
+
+// SetCgroupCpus restricts a cgroup to the given cores.
+func SetCgroupCpus(path string, coreIds []int) error {
+	return sysfs.WriteString(filepath.Join(path, CpusetCpusFile), sysfs.FormatCpuList(coreIds))
+}
+
+// CgroupName returns the path of a cgroup relative to the cgroup v2 mount, which CgroupRoot resolves back.
+func CgroupName(path string) string {
+	if name, isUnderMount := strings.CutPrefix(path, CgroupPath+"/"); isUnderMount {
+		return name
+	}
+	return path
+}
@@ -0,0 +1,61 @@
+package workload
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCgroups(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		CpusetCpusEffectiveFile:                                "0-7\n",
+		"kubepods.slice/" + CpusetCpusEffectiveFile:            "0-7\n",
+		"kubepods.slice/" + CpuStatFile:                        "usage_usec 900\nuser_usec 700\n",
+		"kubepods.slice/pod-a/" + CpusetCpusFile:               "6-7\n",
+		"kubepods.slice/pod-a/" + CpusetCpusEffectiveFile:      "6-7\n",
+		"kubepods.slice/pod-a/" + CpuStatFile:                  "usage_usec 500\nuser_usec 400\n",
+		"kubepods.slice/pod-b/" + CpusetCpusEffectiveFile:      "0-5\n",
+		"kubepods.slice/pod-b/no-cpuset/" + CpuStatFile:        "usage_usec 100\n",
+		"system.slice/" + CpusetCpusFile:                       "\n",
+		"system.slice/" + CpusetCpusEffectiveFile:              "0-1\n",
+		"system.slice/sshd.service/" + CpusetCpusEffectiveFile: "0-1\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(content), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []Cgroup{
+		{Path: root, EffectiveCpus: []int{0, 1, 2, 3, 4, 5, 6, 7}},
+		{Path: "kubepods.slice", EffectiveCpus: []int{0, 1, 2, 3, 4, 5, 6, 7}, UsageUsec: 900},
+		{Path: "kubepods.slice/pod-a", Cpus: []int{6, 7}, EffectiveCpus: []int{6, 7}, UsageUsec: 500, IsLeaf: true},
+		{Path: "kubepods.slice/pod-b", EffectiveCpus: []int{0, 1, 2, 3, 4, 5}, IsLeaf: true},
+		{Path: "system.slice", EffectiveCpus: []int{0, 1}},
+		{Path: "system.slice/sshd.service", EffectiveCpus: []int{0, 1}, IsLeaf: true},
+	}
+	got, err := Cgroups(root)
+	if err != nil {
+		t.Fatalf("Cgroups() failed: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Cgroups() = %+v, want %d groups", got, len(want))
+	}
+	for i, w := range want {
+		if w.Path != root {
+			w.Path = filepath.Join(root, w.Path)
+		}
+		g := got[i]
+		if g.Path != w.Path || !slices.Equal(g.Cpus, w.Cpus) || !slices.Equal(g.EffectiveCpus, w.EffectiveCpus) ||
+			g.UsageUsec != w.UsageUsec || g.IsLeaf != w.IsLeaf {
+			t.Errorf("Cgroups()[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+}
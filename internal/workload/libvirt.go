@@ -0,0 +1,124 @@
+package workload
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/utils"
+	"strconv"
+	"strings"
+)
+
+// DomainPinning is the cpu affinity of the vcpus and the emulator thread of a libvirt domain.
+type DomainPinning struct {
+	Domain   string
+	Vcpus    map[int][]int
+	Emulator []int
+}
+
+type domainsVirshModel struct {
+	Name string `json:"Name"`
+}
+
+type emulatorPinVirshModel struct {
+	EmulatorCPUAffinity string `json:"emulator: CPU Affinity"`
+}
+
+type vcpuPinVirshModel struct {
+	Vcpu        string `json:"VCPU"`
+	CPUAffinity string `json:"CPU Affinity"`
+}
+
+// Domains lists the names of running libvirt domains.
+func Domains() ([]string, error) {
+	var domains []domainsVirshModel
+	err := utils.RunThirdPartyClient[domainsVirshModel](&domains, "virsh-list-domains.sh")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, domain := range domains {
+		names = append(names, domain.Name)
+	}
+	return names, nil
+}
+
+// GetDomainPinning reads the live vcpu and emulator pinning of a domain.
+func GetDomainPinning(domain string) (*DomainPinning, error) {
+	pinning := DomainPinning{Domain: domain, Vcpus: map[int][]int{}}
+
+	var emulatorPins []emulatorPinVirshModel
+	err := utils.RunThirdPartyClient[emulatorPinVirshModel](&emulatorPins, "virsh-domain-get-pinned-cpu-core.sh",
+		domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range emulatorPins {
+		_, cpuList, _ := strings.Cut(pin.EmulatorCPUAffinity, "*: ")
+		coreIds, err := sysfs.ParseCpuList(cpuList)
+		if err != nil {
+			return nil, fmt.Errorf("failed at parsing emulator pinning of domain: %s: %w", domain, err)
+		}
+		pinning.Emulator = append(pinning.Emulator, coreIds...)
+	}
+
+	var vcpuPins []vcpuPinVirshModel
+	err = utils.RunThirdPartyClient[vcpuPinVirshModel](&vcpuPins, "virsh-domain-get-vcpu-pins.sh", domain)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range vcpuPins {
+		vcpu, err := strconv.Atoi(strings.TrimSpace(pin.Vcpu))
+		if err != nil {
+			return nil, fmt.Errorf("failed at parsing vcpu: %s of domain: %s: %w", pin.Vcpu, domain, err)
+		}
+		coreIds, err := sysfs.ParseCpuList(pin.CPUAffinity)
+		if err != nil {
+			return nil, fmt.Errorf("failed at parsing vcpu pinning of domain: %s: %w", domain, err)
+		}
+		pinning.Vcpus[vcpu] = coreIds
+	}
+	return &pinning, nil
+}
+
+// PinVcpu pins a vcpu of a running domain to the given cores.
+func PinVcpu(domain string, vcpu int, coreIds []int) error {
+	err := utils.RunThirdPartyClient[any](nil, "virsh-domain-pin-vcpu.sh", domain, strconv.Itoa(vcpu),
+		sysfs.FormatCpuList(coreIds))
+	if err != nil {
+		return fmt.Errorf("failed at pinning vcpu: %d of domain: %s: %w", vcpu, domain, err)
+	}
+	return nil
+}
+
+// PinEmulator pins the emulator thread of a running domain to the given cores.
+func PinEmulator(domain string, coreIds []int) error {
+	err := utils.RunThirdPartyClient[any](nil, "virsh-domain-pin-emulator.sh", domain, sysfs.FormatCpuList(coreIds))
+	if err != nil {
+		return fmt.Errorf("failed at pinning emulator of domain: %s: %w", domain, err)
+	}
+	return nil
+}
+
+// SetDomainPinning pins the vcpus and the emulator thread of a running domain as given, leaving out an empty emulator
+// pinning.
+func SetDomainPinning(pinning *DomainPinning) error {
+	for vcpu, coreIds := range pinning.Vcpus {
+		err := PinVcpu(pinning.Domain, vcpu, coreIds)
+		if err != nil {
+			return err
+		}
+	}
+	if len(pinning.Emulator) == 0 {
+		return nil
+	}
+	return PinEmulator(pinning.Domain, pinning.Emulator)
+}
+
+// CoreIds lists the distinct cores the domain is pinned to.
+func (p *DomainPinning) CoreIds() []int {
+	coreIds := append([]int{}, p.Emulator...)
+	for _, vcpuCoreIds := range p.Vcpus {
+		coreIds = append(coreIds, vcpuCoreIds...)
+	}
+	return distinct(coreIds)
+}
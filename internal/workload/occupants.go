@@ -0,0 +1,135 @@
+package workload
+
+import (
+	"fmt"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/model"
+	"github.com/crunchycookie/openstack-gc/gc-controller/internal/sysfs"
+	"path/filepath"
+	"slices"
+)
+
+const (
+	DomainOccupant = "domain"
+	CgroupOccupant = "cgroup"
+)
+
+// Occupants lists the libvirt domains and cgroups pinned to any of the given cores. Cgroups are only scanned if a root
+// is given. Only domains and cgroups restricted to a subset of the cores of the host count as pinned, since unpinned
+// ones report every core.
+func Occupants(coreIds []int, libvirt bool, cgroupRoot string) ([]model.Occupant, error) {
+	var occupants []model.Occupant
+	if libvirt {
+		onlineCoreIds, err := sysfs.OnlineCpuIds()
+		if err != nil {
+			return nil, err
+		}
+		domains, err := Domains()
+		if err != nil {
+			return nil, err
+		}
+		for _, domain := range domains {
+			pinning, err := GetDomainPinning(domain)
+			if err != nil {
+				return nil, err
+			}
+			if pinned := pinnedTo(pinning.CoreIds(), onlineCoreIds, coreIds); len(pinned) > 0 {
+				occupants = append(occupants, model.Occupant{Kind: DomainOccupant, Name: domain, CoreIds: pinned})
+			}
+		}
+	}
+	if cgroupRoot != "" {
+		hostCpus, err := sysfs.ReadString(filepath.Join(CgroupPath, CpusetCpusEffectiveFile))
+		if err != nil {
+			return nil, err
+		}
+		hostCoreIds, err := sysfs.ParseCpuList(hostCpus)
+		if err != nil {
+			return nil, err
+		}
+		cgroups, err := Cgroups(cgroupRoot)
+		if err != nil {
+			return nil, err
+		}
+		for _, cgroup := range cgroups {
+			if pinned := pinnedTo(cgroup.Cpus, hostCoreIds, coreIds); len(pinned) > 0 {
+				occupants = append(occupants, model.Occupant{Kind: CgroupOccupant, Name: CgroupName(cgroup.Path),
+					CoreIds: pinned})
+			}
+		}
+	}
+	return occupants, nil
+}
+
+// Evict re-pins an occupant off the given cores. Vcpus, emulator threads and cgroups keep the rest of their cores, and
+// are pinned to the fallback cores if none are left.
+func Evict(occupant model.Occupant, coreIds []int, fallbackCoreIds []int) error {
+	switch occupant.Kind {
+	case DomainOccupant:
+		pinning, err := GetDomainPinning(occupant.Name)
+		if err != nil {
+			return err
+		}
+		for vcpu, vcpuCoreIds := range pinning.Vcpus {
+			if len(intersect(vcpuCoreIds, coreIds)) == 0 {
+				continue
+			}
+			err = PinVcpu(occupant.Name, vcpu, remainingOrFallback(vcpuCoreIds, coreIds, fallbackCoreIds))
+			if err != nil {
+				return err
+			}
+		}
+		if len(intersect(pinning.Emulator, coreIds)) > 0 {
+			return PinEmulator(occupant.Name, remainingOrFallback(pinning.Emulator, coreIds, fallbackCoreIds))
+		}
+		return nil
+	case CgroupOccupant:
+		path := CgroupRoot(occupant.Name)
+		cpus, err := sysfs.ReadString(filepath.Join(path, CpusetCpusFile))
+		if err != nil {
+			return err
+		}
+		cgroupCoreIds, err := sysfs.ParseCpuList(cpus)
+		if err != nil {
+			return err
+		}
+		return SetCgroupCpus(path, remainingOrFallback(cgroupCoreIds, coreIds, fallbackCoreIds))
+	}
+	return fmt.Errorf("unknown occupant kind: %s", occupant.Kind)
+}
+
+// IsPinned tells whether a workload allowed on the given cores is restricted to a subset of the cores of the host.
+func IsPinned(workloadCoreIds []int, hostCoreIds []int) bool {
+	return len(workloadCoreIds) > 0 && len(intersect(hostCoreIds, workloadCoreIds)) < len(distinct(hostCoreIds))
+}
+
+// pinnedTo lists which of the given cores a workload is pinned to, which are none if the workload is not pinned.
+func pinnedTo(workloadCoreIds []int, hostCoreIds []int, coreIds []int) []int {
+	if !IsPinned(workloadCoreIds, hostCoreIds) {
+		return nil
+	}
+	return intersect(workloadCoreIds, coreIds)
+}
+
+func remainingOrFallback(pinned []int, evicted []int, fallback []int) []int {
+	remaining := slices.DeleteFunc(slices.Clone(pinned), func(id int) bool { return slices.Contains(evicted, id) })
+	if len(remaining) == 0 {
+		return fallback
+	}
+	return remaining
+}
+
+func intersect(a []int, b []int) []int {
+	var common []int
+	for _, id := range distinct(a) {
+		if slices.Contains(b, id) {
+			common = append(common, id)
+		}
+	}
+	return common
+}
+
+func distinct(ids []int) []int {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
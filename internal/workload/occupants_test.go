@@ -0,0 +1,30 @@
+package workload
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPinnedTo(t *testing.T) {
+	hostCoreIds := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	dynamicCoreIds := []int{6, 7}
+	cases := []struct {
+		name            string
+		workloadCoreIds []int
+		want            []int
+	}{
+		{name: "unpinned domain reports every host core", workloadCoreIds: []int{0, 1, 2, 3, 4, 5, 6, 7}, want: nil},
+		{name: "unpinned domain with per vcpu duplicates", workloadCoreIds: []int{0, 1, 2, 3, 4, 5, 6, 7, 0, 7},
+			want: nil},
+		{name: "pinned to dynamic cores", workloadCoreIds: []int{6, 7}, want: []int{6, 7}},
+		{name: "pinned across pools", workloadCoreIds: []int{1, 7, 7}, want: []int{7}},
+		{name: "pinned to stable cores", workloadCoreIds: []int{0, 1}, want: nil},
+		{name: "no affinity", workloadCoreIds: nil, want: nil},
+	}
+	for _, c := range cases {
+		got := pinnedTo(c.workloadCoreIds, hostCoreIds, dynamicCoreIds)
+		if !slices.Equal(got, c.want) {
+			t.Errorf("%s: pinnedTo(%v) = %v, want %v", c.name, c.workloadCoreIds, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,4 @@
+#!/bin/bash
+# set/source env vars first.
+# $1 = domain name
+virsh vcpupin $1 | virsh-json
\ No newline at end of file
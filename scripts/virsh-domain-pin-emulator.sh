@@ -0,0 +1,4 @@
+#!/bin/bash
+# set/source env vars first.
+# $1 = domain name, $2 = cpu list
+virsh emulatorpin $1 $2 --live
\ No newline at end of file
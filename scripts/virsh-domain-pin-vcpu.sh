@@ -0,0 +1,4 @@
+#!/bin/bash
+# set/source env vars first.
+# $1 = domain name, $2 = vcpu, $3 = cpu list
+virsh vcpupin $1 $2 $3 --live
\ No newline at end of file